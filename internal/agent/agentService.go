@@ -1,35 +1,57 @@
 package agent
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/isaacphi/slop/internal/config"
 	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/hooks"
+	"github.com/isaacphi/slop/internal/jsonschema"
 	"github.com/isaacphi/slop/internal/llm"
 	"github.com/isaacphi/slop/internal/mcp"
 	"github.com/isaacphi/slop/internal/message"
+	"github.com/isaacphi/slop/internal/tools"
 )
 
 // "Agent" manages the interaction between the message service and function calls
 type Agent struct {
 	messageService *message.MessageService
-	mcp            *mcp.Client
+	mcp            mcp.Service
+	tools          *tools.Registry
+	hooks          *hooks.Runner
 	cfg            config.Agent
 }
 
-// New creates a new "Agent" with the given message service and configuration
-func New(messageService *message.MessageService, mcpClient *mcp.Client, cfg config.Agent) *Agent {
+// New creates a new "Agent" with the given message service, tool providers, hooks and configuration
+func New(messageService *message.MessageService, mcpClient mcp.Service, toolRegistry *tools.Registry, hooksRunner *hooks.Runner, cfg config.Agent) *Agent {
 	return &Agent{
 		messageService: messageService,
 		mcp:            mcpClient,
+		tools:          toolRegistry,
+		hooks:          hooksRunner,
 		cfg:            cfg,
 	}
 }
 
+// getTools returns native and MCP tools together
+func (a *Agent) getTools() map[string]config.Tool {
+	allTools := a.mcp.GetTools()
+	for name, tool := range a.tools.GetTools() {
+		allTools[name] = tool
+	}
+	return allTools
+}
+
 // PendingFunctionCallError is returned when a function call needs user approval
 type PendingFunctionCallError struct {
 	Message  *domain.Message
@@ -40,76 +62,87 @@ func (e *PendingFunctionCallError) Error() string {
 	return fmt.Sprintf("pending function call approval for %s", e.ToolCall.Name)
 }
 
-// validateArguments checks if the provided arguments match the tool's schema
-func validateArguments(args json.RawMessage, tool config.Tool) error {
-	var parsedArgs map[string]interface{}
-	if err := json.Unmarshal(args, &parsedArgs); err != nil {
-		return fmt.Errorf("invalid argument format: %w", err)
-	}
-
-	// Check required parameters
-	for _, required := range tool.Parameters.Required {
-		if _, exists := parsedArgs[required]; !exists {
-			return fmt.Errorf("missing required parameter: %s", required)
+// validateArguments fills in schema defaults and checks the arguments against
+// the tool's JSON Schema, returning the arguments to call the tool with
+func validateArguments(args json.RawMessage, tool config.Tool) (json.RawMessage, error) {
+	var parsedArgs interface{} = map[string]interface{}{}
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &parsedArgs); err != nil {
+			return nil, fmt.Errorf("invalid argument format: %w", err)
 		}
 	}
 
-	// Validate each provided argument
-	for name, value := range parsedArgs {
-		prop, exists := tool.Parameters.Properties[name]
-		if !exists {
-			return fmt.Errorf("unknown parameter: %s", name)
-		}
-
-		// Type validation
-		switch prop.Type {
-		case "string":
-			if _, ok := value.(string); !ok {
-				return fmt.Errorf("parameter %s must be a string", name)
-			}
-		case "number":
-			if _, ok := value.(float64); !ok {
-				return fmt.Errorf("parameter %s must be a number", name)
-			}
-		case "boolean":
-			if _, ok := value.(bool); !ok {
-				return fmt.Errorf("parameter %s must be a boolean", name)
-			}
-		case "array":
-			if _, ok := value.([]interface{}); !ok {
-				return fmt.Errorf("parameter %s must be an array", name)
-			}
-		}
-
-		// Enum validation
-		if len(prop.Enum) > 0 {
-			if strVal, ok := value.(string); ok {
-				valid := false
-				for _, enum := range prop.Enum {
-					if strVal == enum {
-						valid = true
-						break
-					}
-				}
-				if !valid {
-					return fmt.Errorf("parameter %s must be one of: %v", name, prop.Enum)
-				}
-			}
-		}
+	schema := tool.JSONSchema()
+	parsedArgs = jsonschema.ApplyDefaults(schema, parsedArgs)
+	if err := jsonschema.Validate(schema, parsedArgs); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return json.Marshal(parsedArgs)
 }
 
-// executeFunction executes a function call and returns its result
-func (a *Agent) executeFunction(ctx context.Context, toolCall llm.ToolCall, tools map[string]config.Tool) (string, error) {
+// executeFunction executes a function call requested in msg and returns its
+// result. toolCall.Arguments is updated to the arguments the tool was called with.
+func (a *Agent) executeFunction(ctx context.Context, msg *domain.Message, toolCall *llm.ToolCall, tools map[string]config.Tool) (string, error) {
 	tool, exists := tools[toolCall.Name]
 	if !exists {
 		return "", fmt.Errorf("function %s not found", toolCall.Name)
 	}
 
-	if err := validateArguments(toolCall.Arguments, tool); err != nil {
-		return "", fmt.Errorf("argument validation failed: %w", err)
+	// Validation errors are returned as the tool result so the model can correct its call
+	arguments, err := validateArguments(toolCall.Arguments, tool)
+	if err != nil {
+		return "", fmt.Errorf("invalid arguments, correct them and call %s again: %w", toolCall.Name, err)
+	}
+
+	// Hooks may veto the call or rewrite its arguments
+	payload, err := a.hooks.Before(ctx, hooks.Payload{
+		Event:     hooks.BeforeToolCall,
+		ThreadID:  msg.ThreadID.String(),
+		MessageID: msg.ID.String(),
+		Tool:      toolCall.Name,
+		CallID:    toolCall.ID,
+		Arguments: arguments,
+	})
+	if err != nil {
+		return "", err
+	}
+	if !bytes.Equal(payload.Arguments, arguments) {
+		arguments, err = validateArguments(payload.Arguments, tool)
+		if err != nil {
+			return "", fmt.Errorf("arguments rewritten by hook are invalid: %w", err)
+		}
+	}
+	toolCall.Arguments = arguments
+
+	result, err := a.callTool(ctx, *toolCall)
+
+	after := hooks.Payload{
+		Event:     hooks.AfterToolCall,
+		ThreadID:  msg.ThreadID.String(),
+		MessageID: msg.ID.String(),
+		Tool:      toolCall.Name,
+		CallID:    toolCall.ID,
+		Arguments: arguments,
+		Result:    result,
+	}
+	if err != nil {
+		after.Error = err.Error()
+	}
+	a.hooks.After(ctx, after)
+
+	return result, err
+}
+
+// callTool runs a validated tool call on the registry or MCP server that provides it
+func (a *Agent) callTool(ctx context.Context, toolCall llm.ToolCall) (string, error) {
+	// Native tools run in-process
+	if a.tools.Has(toolCall.Name) {
+		result, err := a.tools.CallTool(ctx, toolCall.Name, toolCall.Arguments)
+		if err != nil {
+			return "", fmt.Errorf("function execution failed: %w", err)
+		}
+		return result, nil
 	}
 
 	// Parse arguments into interface{}
@@ -134,33 +167,152 @@ func (a *Agent) executeFunction(ctx context.Context, toolCall llm.ToolCall, tool
 }
 
 // SendMessage sends a message through the "Agent", handling any function calls
+// until the model answers without one or a limit from the agent config is reached
 func (a *Agent) SendMessage(ctx context.Context, opts message.SendMessageOptions) (*domain.Message, error) {
-	opts.Tools = a.mcp.GetTools()
+	return a.run(ctx, opts, nil, nil)
+}
 
-	// Start with normal message flow
-	responseMsg, err := a.messageService.SendMessage(ctx, opts)
+// Resume carries on a thread whose last message requested tool calls that
+// were not run, because a limit stopped the agent or the calls are waiting
+// for approval. The calls are run as approved by the current user, except
+// those that already ran, their results are sent to the model and the agent
+// continues as in SendMessage. opts.Content is not used.
+func (a *Agent) Resume(ctx context.Context, opts message.SendMessageOptions) (*domain.Message, error) {
+	messages, err := a.messageService.GetThreadMessages(ctx, opts.ThreadID, nil)
 	if err != nil {
-		return nil, fmt.Errorf("message service error: %w", err)
+		return nil, fmt.Errorf("failed to get messages: %w", err)
 	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("nothing to resume, the thread has no messages")
+	}
+	last := messages[len(messages)-1]
 
-	_ = opts.StreamHandler.HandleMessageDone()
+	var toolCalls []llm.ToolCall
+	if last.Role == domain.RoleAssistant && last.ToolCalls != "" {
+		if err := json.Unmarshal([]byte(last.ToolCalls), &toolCalls); err != nil {
+			return nil, fmt.Errorf("error unmarshalling tool calls: %w", err)
+		}
+	}
+	if len(toolCalls) == 0 {
+		return nil, fmt.Errorf("nothing to resume, the last message in the thread has no pending tool calls")
+	}
+	return a.run(ctx, opts, &last, toolCalls)
+}
 
-	// Reset stream handler
-	if opts.StreamHandler != nil {
-		opts.StreamHandler.Reset()
+// run is the agent loop. If pending is set, its tool calls are run before the
+// first message is sent, and their results replace opts.Content.
+func (a *Agent) run(ctx context.Context, opts message.SendMessageOptions, pending *domain.Message, pendingCalls []llm.ToolCall) (*domain.Message, error) {
+	modelCfg := a.messageService.GetModelConfig()
+	opts.Tools = tools.Filter(tools.Filter(a.getTools(), modelCfg.ActiveTools), opts.ToolFilter)
+	if opts.Schema != nil {
+		// Structured responses are answered directly, without tools
+		opts.Tools = nil
 	}
 
-	var toolCalls []llm.ToolCall
-	err = json.Unmarshal([]byte(responseMsg.ToolCalls), &toolCalls)
-	if err != nil {
-		return nil, fmt.Errorf("error unmarshalling tool calls: %w", err)
+	parentCtx := ctx
+	if a.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.cfg.Timeout)
+		defer cancel()
 	}
 
-	// Check for function calls in response
-	if len(toolCalls) == 0 {
-		return responseMsg, nil
+	usage := &loopUsage{model: modelCfg}
+	var responseMsg *domain.Message
+
+	if pending != nil {
+		// Calls waiting for approval were recorded when they were requested, and
+		// calls may have run before a timeout stopped the agent
+		executions, err := a.messageService.ListToolExecutions(ctx, domain.ToolExecutionFilter{MessageID: &pending.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tool executions: %w", err)
+		}
+		recorded := make(map[string]*domain.ToolExecution)
+		for i := range executions {
+			// Newest first
+			if _, ok := recorded[executions[i].CallID]; !ok {
+				recorded[executions[i].CallID] = &executions[i]
+			}
+		}
+		results := a.executeFunctions(ctx, pending, pendingCalls, opts.Tools, opts.StreamHandler, currentUser(), recorded)
+		opts = followupOptions(opts, pending, results)
+	}
+
+	for {
+		msg, err := a.messageService.SendMessage(ctx, opts)
+		if err != nil {
+			if ctx.Err() != nil && parentCtx.Err() == nil {
+				return responseMsg, a.limitReached(responseMsg, usage, fmt.Sprintf("timeout of %s reached", a.cfg.Timeout))
+			}
+			return nil, fmt.Errorf("message service error: %w", err)
+		}
+		responseMsg = msg
+		usage.add(responseMsg)
+
+		if opts.StreamHandler != nil {
+			_ = opts.StreamHandler.HandleMessageDone()
+			_ = opts.StreamHandler.HandleUsage(responseMsg.InputTokens, responseMsg.OutputTokens)
+			opts.StreamHandler.Reset()
+		}
+
+		var toolCalls []llm.ToolCall
+		err = json.Unmarshal([]byte(responseMsg.ToolCalls), &toolCalls)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshalling tool calls: %w", err)
+		}
+
+		// Check for function calls in response
+		if len(toolCalls) == 0 {
+			return responseMsg, nil
+		}
+
+		// If auto-approve is disabled, return for manual approval with first tool call
+		if !a.cfg.AutoApproveFunctions {
+			for _, tc := range toolCalls {
+				exec := a.newToolExecution(responseMsg, tc, domain.ApprovalPending, "")
+				if err := a.messageService.RecordToolExecution(ctx, exec); err != nil {
+					return nil, fmt.Errorf("failed to record tool execution: %w", err)
+				}
+			}
+			return responseMsg, &PendingFunctionCallError{
+				Message:  responseMsg,
+				ToolCall: toolCalls[0],
+			}
+		}
+
+		// Stop before running more tools if a limit has been reached
+		if reason := a.checkLimits(usage); reason != "" {
+			return responseMsg, a.limitReached(responseMsg, usage, reason)
+		}
+		if ctx.Err() != nil {
+			if parentCtx.Err() != nil {
+				return responseMsg, parentCtx.Err()
+			}
+			return responseMsg, a.limitReached(responseMsg, usage, fmt.Sprintf("timeout of %s reached", a.cfg.Timeout))
+		}
+
+		results := a.executeFunctions(ctx, responseMsg, toolCalls, opts.Tools, opts.StreamHandler, domain.ApprovedByConfig, nil)
+		opts = followupOptions(opts, responseMsg, results)
 	}
+}
 
+// followupOptions sends combined tool results as a reply to msg
+func followupOptions(opts message.SendMessageOptions, msg *domain.Message, results string) message.SendMessageOptions {
+	return message.SendMessageOptions{
+		ThreadID:      opts.ThreadID,
+		ParentID:      &msg.ID,
+		Content:       results,
+		StreamHandler: opts.StreamHandler,
+		Tools:         opts.Tools,
+		ToolFilter:    opts.ToolFilter,
+	}
+}
+
+// executeFunctions runs tool calls concurrently and formats their results
+// as a single followup message. Every call is recorded in the tool execution
+// log as approved by approvedBy before it runs, and a call that cannot be
+// recorded is not run. Calls with a record in recorded reuse it if it is
+// pending, otherwise they are not run again and report the recorded outcome.
+func (a *Agent) executeFunctions(ctx context.Context, msg *domain.Message, toolCalls []llm.ToolCall, tools map[string]config.Tool, handler message.StreamHandler, approvedBy string, recorded map[string]*domain.ToolExecution) string {
 	// Create channels for collecting results
 	type toolResult struct {
 		call   llm.ToolCall
@@ -172,13 +324,61 @@ func (a *Agent) SendMessage(ctx context.Context, opts message.SendMessageOptions
 	// Launch concurrent execution of all tool calls
 	for _, call := range toolCalls {
 		go func(tc llm.ToolCall) {
-			if a.cfg.AutoApproveFunctions {
-				result, err := a.executeFunction(ctx, tc, opts.Tools)
-				resultChan <- toolResult{
-					call:   tc,
-					result: result,
-					err:    err,
+			exec, ok := recorded[tc.ID]
+			if ok && exec.Approval != domain.ApprovalPending {
+				// Already run or denied, report the recorded outcome
+				res := toolResult{call: tc, result: exec.Result}
+				if exec.Error != "" {
+					res.err = errors.New(exec.Error)
 				}
+				resultChan <- res
+				return
+			}
+			var err error
+			if ok {
+				exec.Approval = domain.ApprovalApproved
+				exec.ApprovedBy = approvedBy
+				err = a.messageService.UpdateToolExecution(ctx, exec)
+			} else {
+				exec = a.newToolExecution(msg, tc, domain.ApprovalApproved, approvedBy)
+				err = a.messageService.RecordToolExecution(ctx, exec)
+			}
+			if err != nil {
+				resultChan <- toolResult{call: tc, err: fmt.Errorf("not run, failed to record tool execution: %w", err)}
+				return
+			}
+
+			toolCtx := ctx
+			if a.cfg.ToolTimeout > 0 {
+				var cancel context.CancelFunc
+				toolCtx, cancel = context.WithTimeout(ctx, a.cfg.ToolTimeout)
+				defer cancel()
+			}
+			start := time.Now()
+			result, err := a.executeFunction(toolCtx, msg, &tc, tools)
+			if err != nil && errors.Is(toolCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+				err = fmt.Errorf("tool call timed out after %s", a.cfg.ToolTimeout)
+			}
+
+			exec.Duration = time.Since(start)
+			exec.Arguments = string(tc.Arguments)
+			exec.Result = result
+			if err != nil {
+				exec.Error = err.Error()
+			}
+			var denied *hooks.DeniedError
+			if errors.As(err, &denied) {
+				exec.Approval = domain.ApprovalDenied
+				exec.ApprovedBy = "hook:" + denied.Hook
+			}
+			if err := a.messageService.UpdateToolExecution(context.WithoutCancel(ctx), exec); err != nil {
+				slog.Error("failed to record tool execution result", "tool", tc.Name, "id", exec.ID, "error", err)
+			}
+
+			resultChan <- toolResult{
+				call:   tc,
+				result: result,
+				err:    err,
 			}
 		}(call)
 	}
@@ -189,6 +389,9 @@ func (a *Agent) SendMessage(ctx context.Context, opts message.SendMessageOptions
 
 	for i := 0; i < len(toolCalls); i++ {
 		res := <-resultChan
+		if handler != nil {
+			_ = handler.HandleToolResult(res.call.ID, res.call.Name, res.result, res.err)
+		}
 
 		// Format the tool call header
 		fmt.Fprintf(&combinedResults, "Name: %s\n", res.call.Name)
@@ -209,29 +412,46 @@ func (a *Agent) SendMessage(ctx context.Context, opts message.SendMessageOptions
 		}
 	}
 
-	// If auto-approve is disabled, return for manual approval with first tool call
-	if !a.cfg.AutoApproveFunctions {
-		return responseMsg, &PendingFunctionCallError{
-			Message:  responseMsg,
-			ToolCall: toolCalls[0],
-		}
-	}
-
-	fmt.Printf("\n%s\n", combinedResults.String())
+	return combinedResults.String()
+}
 
-	// Send combined results as followup message
-	followupOpts := message.SendMessageOptions{
-		ThreadID:      opts.ThreadID,
-		ParentID:      &responseMsg.ID,
-		Content:       combinedResults.String(),
-		StreamHandler: opts.StreamHandler,
+// newToolExecution creates the audit record for a tool call requested in msg
+func (a *Agent) newToolExecution(msg *domain.Message, tc llm.ToolCall, approval domain.ApprovalDecision, approvedBy string) *domain.ToolExecution {
+	server := "native"
+	if !a.tools.Has(tc.Name) {
+		server, _, _ = strings.Cut(tc.Name, "__")
+	}
+	return &domain.ToolExecution{
+		CallID:     tc.ID,
+		ThreadID:   msg.ThreadID,
+		MessageID:  msg.ID,
+		Server:     server,
+		Tool:       tc.Name,
+		Arguments:  string(tc.Arguments),
+		Approval:   approval,
+		ApprovedBy: approvedBy,
 	}
-
-	return a.SendMessage(ctx, followupOpts)
 }
 
 // DenyFunctionCall handles a denied function call
 func (a *Agent) DenyFunctionCall(ctx context.Context, threadID uuid.UUID, messageID uuid.UUID, reason string) (*domain.Message, error) {
+	// Record the decision on the pending executions for this message
+	executions, err := a.messageService.ListToolExecutions(ctx, domain.ToolExecutionFilter{MessageID: &messageID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tool executions: %w", err)
+	}
+	for i := range executions {
+		if executions[i].Approval != domain.ApprovalPending {
+			continue
+		}
+		executions[i].Approval = domain.ApprovalDenied
+		executions[i].ApprovedBy = currentUser()
+		executions[i].Error = reason
+		if err := a.messageService.UpdateToolExecution(ctx, &executions[i]); err != nil {
+			return nil, fmt.Errorf("failed to record tool execution: %w", err)
+		}
+	}
+
 	content := fmt.Sprintf("Function call denied: %s\nPlease suggest an alternative approach.", reason)
 	return a.messageService.SendMessage(ctx, message.SendMessageOptions{
 		ThreadID: threadID,
@@ -239,3 +459,11 @@ func (a *Agent) DenyFunctionCall(ctx context.Context, threadID uuid.UUID, messag
 		Content:  content,
 	})
 }
+
+// currentUser names the local user for approval records
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return "user:" + u.Username
+	}
+	return "user:" + os.Getenv("USER")
+}
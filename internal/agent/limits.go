@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+// LimitReachedError is returned when the agent stops calling tools because
+// one of maxSteps, timeout, tokenBudget or costBudget was reached. Message is
+// the last response received, if any.
+type LimitReachedError struct {
+	Message *domain.Message
+	Reason  string
+	Steps   int
+	Tokens  int
+	Cost    float64
+}
+
+func (e *LimitReachedError) Error() string {
+	return fmt.Sprintf("stopped after %d steps: %s", e.Steps, e.Reason)
+}
+
+// loopUsage accumulates usage across the model calls of one SendMessage
+type loopUsage struct {
+	model  config.Model
+	steps  int
+	tokens int
+	cost   float64
+}
+
+func (u *loopUsage) add(msg *domain.Message) {
+	u.steps++
+	u.tokens += msg.InputTokens + msg.OutputTokens
+	u.cost += u.model.Cost(msg.InputTokens, msg.OutputTokens)
+}
+
+// checkLimits returns a description of the first limit that has been reached,
+// or an empty string if the agent may continue
+func (a *Agent) checkLimits(usage *loopUsage) string {
+	switch {
+	case a.cfg.MaxSteps > 0 && usage.steps >= a.cfg.MaxSteps:
+		return fmt.Sprintf("maxSteps limit of %d reached", a.cfg.MaxSteps)
+	case a.cfg.TokenBudget > 0 && usage.tokens >= a.cfg.TokenBudget:
+		return fmt.Sprintf("tokenBudget of %d reached (%d tokens used)", a.cfg.TokenBudget, usage.tokens)
+	case a.cfg.CostBudget > 0 && usage.cost >= a.cfg.CostBudget:
+		return fmt.Sprintf("costBudget of $%.4f reached ($%.4f spent)", a.cfg.CostBudget, usage.cost)
+	}
+	return ""
+}
+
+func (a *Agent) limitReached(msg *domain.Message, usage *loopUsage, reason string) *LimitReachedError {
+	return &LimitReachedError{
+		Message: msg,
+		Reason:  reason,
+		Steps:   usage.steps,
+		Tokens:  usage.tokens,
+		Cost:    usage.cost,
+	}
+}
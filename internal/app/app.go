@@ -11,6 +11,24 @@ import (
 	"github.com/isaacphi/slop/internal/config"
 )
 
+// SkipInit is a cobra annotation for commands that run without loading the
+// config, such as those that repair an invalid config
+const SkipInit = "skipInit"
+
+// ExitStopped is the exit status of a command that stopped before finishing
+// its work, such as an agent run that reached a limit
+const ExitStopped = 2
+
+// ExitError makes the command that returns it exit with Code instead of 1
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string { return e.Err.Error() }
+
+func (e *ExitError) Unwrap() error { return e.Err }
+
 // App holds the global application state
 type App struct {
 	Config *config.ConfigSchema
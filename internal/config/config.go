@@ -2,14 +2,20 @@ package config
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
 /*
@@ -17,16 +23,19 @@ Config System Design:
 This configuration system implements a hierarchical config with the following precedence
 (highest to lowest priority):
 
-1. Command line overrides
-2. Local project config (.slop/*.slop.{yaml,json})
-3. Global user config ($XDG_CONFIG_HOME/slop/*.slop.{yaml,json})
-4. Default values (from defaults.slop.yaml)
+1. Command line overrides (--set, --log-level, --log-file)
+2. Environment variables (SLOP_*, see overrides.go)
+3. The selected profile (--profile or SLOP_PROFILE)
+4. Local project config (.slop/*.slop.{yaml,json}, see LocalDir)
+5. Global user config ($XDG_CONFIG_HOME/slop/*.slop.{yaml,json})
+6. Default values (from defaults.slop.yaml)
 
 The system supports:
 - Multiple config files in each directory, merged alphabetically
 - Automatic merging of lists (they combine)
 - Deep merging of maps
 - Override of scalar values
+- Merge directives to replace or remove values instead, see merge.go
 - Schema validation of the final config
 
 Example:
@@ -34,6 +43,14 @@ If you have these files:
 ~/.config/slop/models.slop.yaml:  { models: ["gpt-4"] }
 ./.slop/models.slop.yaml:         { models: ["claude"] }
 The result will be: { models: ["gpt-4", "claude"] }
+
+Profiles are named sets of values under the top level profiles key, in any
+config file. The selected profile is merged over everything else:
+
+	profiles:
+	  work:
+	    activeModel: gateway
+	    agent: { autoApproveFunctions: false }
 */
 
 // Config holds the configuration state
@@ -42,6 +59,23 @@ type Config struct {
 	mu       sync.RWMutex
 	sources  map[string]string
 	warnings []string
+
+	// Contents to use instead of files on disk, by absolute path, see Validate
+	replacement map[string][]byte
+
+	// Flattened values of each profile, with the file that set them
+	profiles map[string]map[string]profileValue
+
+	// Source of each element of list values
+	elements map[string][]string
+
+	// Values set by each layer, in order, see Explain
+	changes []Change
+}
+
+type profileValue struct {
+	value  interface{}
+	source string
 }
 
 // RuntimeOverrides holds configuration values that can be overridden at runtime
@@ -49,30 +83,25 @@ type Config struct {
 type RuntimeOverrides struct {
 	LogLevel *string
 	LogFile  *string
+	Profile  *string  // Defaults to SLOP_PROFILE
+	Set      []string // key=value pairs from --set
 }
 
 // Instantiate a new ConfigSchema
 func New(overrides *RuntimeOverrides) (*ConfigSchema, error) {
-	c := &Config{
-		v:        viper.New(),
-		sources:  make(map[string]string),
-		warnings: make([]string, 0),
-	}
-
-	// Load defaults first
-	if err := c.loadDefaults(); err != nil {
-		return nil, fmt.Errorf("error loading defaults: %w", err)
-	}
-
-	// Load configs
-	if err := c.loadConfigs(); err != nil {
-		return nil, err
+	profile := os.Getenv("SLOP_PROFILE")
+	var sets []string
+	if overrides != nil {
+		if overrides.Profile != nil {
+			profile = *overrides.Profile
+		}
+		sets = overrides.Set
 	}
 
-	// Validate and create type-safe config
-	schema, err := c.validateConfig()
+	c := newConfig(nil)
+	schema, err := c.load(profile, sets)
 	if err != nil {
-		return nil, fmt.Errorf("config validation error: %w", err)
+		return nil, err
 	}
 
 	// Apply overrides
@@ -89,11 +118,118 @@ func New(overrides *RuntimeOverrides) (*ConfigSchema, error) {
 
 	// Add sources to schema for printing
 	schema.sources = c.sources
+	schema.elements = c.elements
 	schema.warnings = c.warnings
+	schema.profile = profile
+	schema.changes = c.changes
+
+	return schema, nil
+}
+
+func newConfig(replacement map[string][]byte) *Config {
+	return &Config{
+		v:           viper.New(),
+		sources:     make(map[string]string),
+		warnings:    make([]string, 0),
+		replacement: replacement,
+		profiles:    make(map[string]map[string]profileValue),
+		elements:    make(map[string][]string),
+	}
+}
+
+// load reads defaults and config files, applies profile if it is set and
+// then overrides from the environment and sets, and validates the result
+func (c *Config) load(profile string, sets []string) (*ConfigSchema, error) {
+	// Load defaults first
+	if err := c.loadDefaults(); err != nil {
+		return nil, fmt.Errorf("error loading defaults: %w", err)
+	}
+
+	// Load configs
+	if err := c.loadConfigs(); err != nil {
+		return nil, err
+	}
+
+	if profile != "" {
+		if err := c.applyProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+
+	c.interpolate()
+	c.applyEnv(os.Environ())
+	if err := c.applySets(sets); err != nil {
+		return nil, err
+	}
 
+	// Validate and create type-safe config
+	schema, err := c.validateConfig()
+	if err != nil {
+		return nil, fmt.Errorf("config validation error: %w", err)
+	}
+	schema.DBPath = c.resolvePath("dbpath", schema.DBPath)
 	return schema, nil
 }
 
+// resolvePath makes a relative path relative to the config file that set
+// key. Defaults are relative to LocalDir, and values from the environment or
+// --set to the working directory.
+func (c *Config) resolvePath(key, path string) string {
+	path = ExpandHome(path)
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	source := c.sources[key]
+	if i := strings.Index(source, ", with $"); i >= 0 {
+		source = source[:i]
+	}
+	if strings.HasPrefix(source, "profile ") {
+		_, source, _ = strings.Cut(source, ", ")
+	}
+	switch {
+	case source == "" || source == "default":
+		return filepath.Join(LocalDir(), path)
+	case isConfigFile(source):
+		return filepath.Join(filepath.Dir(source), path)
+	default:
+		return path
+	}
+}
+
+// applyProfile merges a profile's values over the loaded config
+func (c *Config) applyProfile(name string) error {
+	values, ok := c.profiles[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("profile %q not found, available profiles: %v", name, c.profileNames())
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		pv := values[key]
+		source := fmt.Sprintf("profile %s, %s", name, pv.source)
+		// Track the source of the maps containing the key, as flattenAndTrack does
+		parts := strings.Split(key, ".")
+		for i := range parts[:len(parts)-1] {
+			c.sources[strings.Join(parts[:i+1], ".")] = source
+		}
+		c.mergeValue(key, pv.value, source)
+	}
+	return nil
+}
+
+func (c *Config) profileNames() []string {
+	names := make([]string, 0, len(c.profiles))
+	for name := range c.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // loadDefaults loads the embedded default configuration
 func (c *Config) loadDefaults() error {
 	v := viper.New()
@@ -115,41 +251,128 @@ func (c *Config) loadConfigs() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Find global config directory
-	xdgConfig := os.Getenv("XDG_CONFIG_HOME")
-	if xdgConfig == "" {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return err
-		}
-		xdgConfig = filepath.Join(home, ".config")
+	globalDir, err := GlobalDir()
+	if err != nil {
+		return err
 	}
-	globalDir := filepath.Join(xdgConfig, "slop")
-	localDir := ".slop"
 
 	// Load files from both locations
-	for _, dir := range []string{globalDir, localDir} {
+	loaded := make(map[string]bool)
+	for _, dir := range []string{globalDir, LocalDir()} {
 		files, err := findConfigFiles(dir)
 		if err != nil && !os.IsNotExist(err) {
 			return err
 		}
+		files = c.addReplacements(dir, files)
 
 		for _, f := range files {
-			v := viper.New()
-			v.SetConfigFile(f)
-			if err := v.ReadInConfig(); err != nil {
-				return fmt.Errorf("error reading config file %s: %w", f, err)
+			if err := c.loadFile(f); err != nil {
+				return err
 			}
-
-			settings := v.AllSettings()
-			if err := c.mergeConfig(settings, f); err != nil {
-				return fmt.Errorf("error merging config from %s: %w", f, err)
+			if abs, err := filepath.Abs(f); err == nil {
+				loaded[abs] = true
 			}
 		}
 	}
+
+	// Files being validated from elsewhere take precedence over everything
+	var extra []string
+	for path := range c.replacement {
+		if !loaded[path] {
+			extra = append(extra, path)
+		}
+	}
+	sort.Strings(extra)
+	for _, f := range extra {
+		if err := c.loadFile(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadFile merges a config file, or the contents replacing it
+func (c *Config) loadFile(f string) error {
+	v := viper.New()
+	abs, _ := filepath.Abs(f)
+	if content, ok := c.replacement[abs]; ok {
+		v.SetConfigType(strings.TrimPrefix(filepath.Ext(f), "."))
+		if err := v.ReadConfig(bytes.NewReader(content)); err != nil {
+			return fmt.Errorf("error reading config file %s: %w", f, err)
+		}
+	} else {
+		v.SetConfigFile(f)
+		if err := v.ReadInConfig(); err != nil {
+			return fmt.Errorf("error reading config file %s: %w", f, err)
+		}
+	}
+
+	settings := v.AllSettings()
+	if err := c.mergeConfig(settings, f); err != nil {
+		return fmt.Errorf("error merging config from %s: %w", f, err)
+	}
 	return nil
 }
 
+// GlobalDir returns the user config directory, $XDG_CONFIG_HOME/slop
+func GlobalDir() (string, error) {
+	xdgConfig := os.Getenv("XDG_CONFIG_HOME")
+	if xdgConfig == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		xdgConfig = filepath.Join(home, ".config")
+	}
+	return filepath.Join(xdgConfig, "slop"), nil
+}
+
+// localDirName is the name of project config directories
+const localDirName = ".slop"
+
+// localDir is set by SetLocalDir
+var localDir string
+
+// SetLocalDir sets the project config directory, instead of searching for
+// one. An empty dir restores the search.
+func SetLocalDir(dir string) {
+	localDir = dir
+}
+
+// LocalDir returns the project config directory: the nearest .slop directory
+// in the working directory or its parents, stopping at the repository root
+// or home directory. If there is none it is .slop in the working directory,
+// where it would be created.
+func LocalDir() string {
+	if localDir != "" {
+		return localDir
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return localDirName
+	}
+	home, _ := os.UserHomeDir()
+	for dir := cwd; ; {
+		if info, err := os.Stat(filepath.Join(dir, localDirName)); err == nil && info.IsDir() {
+			// Relative paths keep sources short, e.g. .slop/config.slop.yaml
+			if rel, err := filepath.Rel(cwd, filepath.Join(dir, localDirName)); err == nil {
+				return rel
+			}
+			return filepath.Join(dir, localDirName)
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil || dir == home {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return localDirName
+}
+
 // findConfigFiles returns all *.slop.{yaml,json} files in a directory
 func findConfigFiles(dir string) ([]string, error) {
 	var files []string
@@ -162,66 +385,211 @@ func findConfigFiles(dir string) ([]string, error) {
 		if entry.IsDir() {
 			continue
 		}
-		name := entry.Name()
-		if strings.HasSuffix(name, "slop.yaml") ||
-			strings.HasSuffix(name, "slop.json") {
-			files = append(files, filepath.Join(dir, name))
+		if isConfigFile(entry.Name()) {
+			files = append(files, filepath.Join(dir, entry.Name()))
 		}
 	}
 	return files, nil
 }
 
+func isConfigFile(name string) bool {
+	return strings.HasSuffix(name, "slop.yaml") ||
+		strings.HasSuffix(name, "slop.json")
+}
+
 // Validate the config against the schema and custom rules
 func (c *Config) validateConfig() (*ConfigSchema, error) {
+	if err := c.resolveExtends(); err != nil {
+		return nil, err
+	}
+
 	var schema ConfigSchema
-	if err := c.v.Unmarshal(&schema); err != nil {
-		return nil, fmt.Errorf("error unmarshaling config: %w", err)
+	hooks := viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		// Viper's default hooks
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		apiKeyHook,
+	))
+	if err := c.v.Unmarshal(&schema, hooks); err != nil {
+		return nil, fmt.Errorf("error unmarshaling config: %s", c.annotateKeys(err.Error()))
+	}
+	if err := c.restoreToolSchemas(&schema); err != nil {
+		return nil, err
 	}
 
 	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return field.Tag.Get("mapstructure")
+	})
 	if err := validate.Struct(schema); err != nil {
-		return nil, fmt.Errorf("config validation error: %w", err)
+		var fieldErrors validator.ValidationErrors
+		if !errors.As(err, &fieldErrors) {
+			return nil, err
+		}
+		var problems []string
+		for _, fe := range fieldErrors {
+			key := strings.TrimPrefix(fe.Namespace(), "ConfigSchema.")
+			problems = append(problems, fmt.Sprintf("%s: %s%s", key, describeFieldError(fe), c.sourceSuffix(key)))
+		}
+		return nil, errors.New(strings.Join(problems, "; "))
 	}
 
 	// Additional custom validations
-	if schema.ActiveModel != "" {
-		found := false
-		for key := range schema.Models {
-			if key == schema.ActiveModel {
-				found = true
-				break
+	if err := c.validateModels(&schema); err != nil {
+		return nil, err
+	}
+
+	return &schema, nil
+}
+
+// describeFieldError explains a failed validate tag
+func describeFieldError(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return fmt.Sprintf("must be one of %s, got %q", strings.Join(strings.Fields(fe.Param()), ", "), fmt.Sprint(fe.Value()))
+	case "gte":
+		return fmt.Sprintf("must be at least %s, got %v", fe.Param(), fe.Value())
+	case "lte":
+		return fmt.Sprintf("must be at most %s, got %v", fe.Param(), fe.Value())
+	case "url":
+		return fmt.Sprintf("must be a URL, got %q", fmt.Sprint(fe.Value()))
+	case "excluded_with":
+		return fmt.Sprintf("cannot be set together with %s", strings.Join(strings.Fields(strings.ToLower(fe.Param())), " or "))
+	default:
+		return fmt.Sprintf("failed %q validation", fe.Tag())
+	}
+}
+
+var quotedKeyPattern = regexp.MustCompile(`'([^'\s]+)'`)
+
+// annotateKeys adds the file that set each 'key' quoted in a decoding error
+func (c *Config) annotateKeys(msg string) string {
+	return quotedKeyPattern.ReplaceAllStringFunc(msg, func(quoted string) string {
+		return quoted + c.sourceSuffix(quoted[1:len(quoted)-1])
+	})
+}
+
+// sourceSuffix describes where key, or the nearest parent of it, was set,
+// with the line for config files. Keys may use brackets for map entries,
+// e.g. models[gpt].provider.
+func (c *Config) sourceSuffix(key string) string {
+	key = strings.ToLower(strings.NewReplacer("[", ".", "]", "").Replace(key))
+	for key != "" {
+		if source, ok := c.sources[key]; ok {
+			if line := c.sourceLine(source, key); line > 0 {
+				return fmt.Sprintf(" (set in %s:%d)", source, line)
 			}
+			return fmt.Sprintf(" (set in %s)", source)
 		}
-		if !found {
-			var availableModels []string
-			for key := range schema.Models {
-				availableModels = append(availableModels, key)
-			}
-			return nil, fmt.Errorf("mainModel %q must be one of configured models: %v",
-				schema.ActiveModel, availableModels)
+		i := strings.LastIndex(key, ".")
+		if i < 0 {
+			break
 		}
+		key = key[:i]
 	}
+	return ""
+}
 
-	return &schema, nil
+// sourceLine returns the line of key in the config file source names, or 0
+func (c *Config) sourceLine(source, key string) int {
+	if i := strings.Index(source, ", with $"); i >= 0 {
+		source = source[:i]
+	}
+	path := strings.Split(key, ".")
+	if strings.HasPrefix(source, "profile ") {
+		var name string
+		name, source, _ = strings.Cut(strings.TrimPrefix(source, "profile "), ", ")
+		path = append([]string{"profiles", name}, path...)
+	}
+	if !isConfigFile(source) || strings.Contains(source, " + ") {
+		return 0
+	}
+
+	content, ok := c.replacement[source]
+	if abs, err := filepath.Abs(source); err == nil && !ok {
+		content, ok = c.replacement[abs]
+	}
+	if !ok {
+		var err error
+		if content, err = os.ReadFile(source); err != nil {
+			return 0
+		}
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return 0
+	}
+	keyNode, _, found := findKey(&doc, path)
+	if !found {
+		return 0
+	}
+	return keyNode.Line
 }
 
 // Merge settings into the main Config.v viper instance
 func (c *Config) mergeConfig(settings map[string]interface{}, source string) error {
+	// Profiles are kept aside until one is selected
+	if profiles, ok := settings["profiles"]; ok {
+		if err := c.addProfiles(profiles, source); err != nil {
+			return err
+		}
+		rest := make(map[string]interface{}, len(settings))
+		for k, v := range settings {
+			if k != "profiles" {
+				rest[k] = v
+			}
+		}
+		settings = rest
+	}
+
 	// Combine flattening and source tracking in one pass
-	flat := c.flattenAndTrack(settings, "", source)
+	flat, err := c.flattenAndTrack(settings, "", source)
+	if err != nil {
+		return err
+	}
+
+	c.applyLayer(flat, source)
+	return nil
+}
 
-	// Set each value in Viper
-	for key, value := range flat {
-		c.v.Set(key, value)
+// addProfiles records the values of each profile in a config file
+func (c *Config) addProfiles(profiles interface{}, source string) error {
+	byName, ok := profiles.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("profiles must be a map of profile names to config values")
+	}
+	for name, settings := range byName {
+		settingsMap, ok := settings.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("profile %q must be a map of config values", name)
+		}
+		name = strings.ToLower(name)
+		if c.profiles[name] == nil {
+			c.profiles[name] = make(map[string]profileValue)
+		}
+		// Flatten without tracking, sources are only recorded if the profile is applied
+		flat, err := (&Config{sources: make(map[string]string)}).flattenAndTrack(settingsMap, "", source)
+		if err != nil {
+			return fmt.Errorf("profile %q: %w", name, err)
+		}
+		for key, value := range flat {
+			c.profiles[name][key] = profileValue{value: value, source: source}
+		}
 	}
 	return nil
 }
 
-// Build a map of js style dot notation settings to their source
-func (c *Config) flattenAndTrack(m map[string]interface{}, prefix string, source string) map[string]interface{} {
+// Build a map of js style dot notation settings to their source. Maps with
+// a _merge key are included as a mergeDirective before their values.
+func (c *Config) flattenAndTrack(m map[string]interface{}, prefix string, source string) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 
 	for k, v := range m {
+		if k == mergeKey {
+			continue
+		}
 		key := k
 		if prefix != "" {
 			key = prefix + "." + k
@@ -231,12 +599,6 @@ func (c *Config) flattenAndTrack(m map[string]interface{}, prefix string, source
 		c.sources[key] = source
 
 		switch val := v.(type) {
-		case map[string]interface{}:
-			// Recursively flatten nested maps
-			flattened := c.flattenAndTrack(val, key, source)
-			for fk, fv := range flattened {
-				result[fk] = fv
-			}
 		case map[interface{}]interface{}:
 			// Convert to map[string]interface{} and recurse
 			stringMap := make(map[string]interface{})
@@ -245,7 +607,26 @@ func (c *Config) flattenAndTrack(m map[string]interface{}, prefix string, source
 					stringMap[skeyStr] = mv
 				}
 			}
-			flattened := c.flattenAndTrack(stringMap, key, source)
+			v = stringMap
+		}
+
+		switch val := v.(type) {
+		case map[string]interface{}:
+			d, ok, err := directive(val, key)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				result[key] = d
+				if d == mergeRemove {
+					continue
+				}
+			}
+			// Recursively flatten nested maps
+			flattened, err := c.flattenAndTrack(val, key, source)
+			if err != nil {
+				return nil, err
+			}
 			for fk, fv := range flattened {
 				result[fk] = fv
 			}
@@ -254,5 +635,5 @@ func (c *Config) flattenAndTrack(m map[string]interface{}, prefix string, source
 		}
 	}
 
-	return result
+	return result, nil
 }
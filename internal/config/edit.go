@@ -0,0 +1,327 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scope selects the config directory that commands write to
+type Scope int
+
+const (
+	ScopeLocal Scope = iota
+	ScopeGlobal
+)
+
+// DefaultFile is the file written in a config directory when no other file sets a key
+const DefaultFile = "config.slop.yaml"
+
+// Dir returns the config directory for the scope
+func (s Scope) Dir() (string, error) {
+	if s == ScopeGlobal {
+		return GlobalDir()
+	}
+	return LocalDir(), nil
+}
+
+// TargetFile returns the file in scope to write key to: the last file that
+// sets it, since that one takes precedence, or DefaultFile
+func TargetFile(scope Scope, key string) (string, error) {
+	dir, err := scope.Dir()
+	if err != nil {
+		return "", err
+	}
+	files, err := findConfigFiles(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	path := splitKey(key)
+	for i := len(files) - 1; i >= 0; i-- {
+		doc, err := readNode(files[i])
+		if err != nil {
+			return "", err
+		}
+		if _, _, found := findKey(doc, path); found {
+			return files[i], nil
+		}
+	}
+	return filepath.Join(dir, DefaultFile), nil
+}
+
+// SetValue sets key to value in file. The value is parsed as YAML, so 25 is a
+// number and [a, b] a list. The file is created if it does not exist, and is
+// only written if the configuration is still valid with the change.
+func SetValue(file, key, value string) error {
+	var parsed yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil || len(parsed.Content) == 0 {
+		parsed = yaml.Node{Content: []*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}}}
+	}
+	valueNode := parsed.Content[0]
+
+	return EditFile(file, func(doc *yaml.Node) error {
+		mapping := root(doc)
+		path := splitKey(key)
+		for i, part := range path {
+			keyNode, valNode, found := lookup(mapping, part)
+			if i == len(path)-1 {
+				if found {
+					valueNode.LineComment = valNode.LineComment
+					*valNode = *valueNode
+				} else {
+					mapping.Content = append(mapping.Content,
+						&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part},
+						valueNode)
+				}
+				return nil
+			}
+			if !found {
+				keyNode = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part}
+				valNode = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				mapping.Content = append(mapping.Content, keyNode, valNode)
+			}
+			if valNode.Kind != yaml.MappingNode {
+				return fmt.Errorf("%s in %s is not a map", strings.Join(path[:i+1], "."), file)
+			}
+			mapping = valNode
+		}
+		return nil
+	})
+}
+
+// UnsetValue removes key from file, along with any maps left empty
+func UnsetValue(file, key string) error {
+	return EditFile(file, func(doc *yaml.Node) error {
+		if !removeKey(root(doc), splitKey(key)) {
+			return fmt.Errorf("%s is not set in %s", key, file)
+		}
+		return nil
+	})
+}
+
+// EditFile applies edit to the parsed contents of a config file and writes
+// it back, keeping comments. The configuration is validated with the new
+// contents first and nothing is written if it is invalid.
+func EditFile(file string, edit func(doc *yaml.Node) error) error {
+	doc, err := readNode(file)
+	if err != nil {
+		return err
+	}
+	if err := edit(doc); err != nil {
+		return err
+	}
+
+	content, err := encodeNode(file, doc)
+	if err != nil {
+		return err
+	}
+	if err := Validate(map[string][]byte{file: content}); err != nil {
+		return fmt.Errorf("not saving %s: %w", file, err)
+	}
+
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(file); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, content, mode)
+}
+
+// Validate loads the configuration the same way New does, with the given
+// files replaced by new contents. Files that would not otherwise be loaded
+// are loaded last. The configuration is checked without a profile and with
+// each profile in turn.
+func Validate(replacements map[string][]byte) error {
+	abs := make(map[string][]byte, len(replacements))
+	for path, content := range replacements {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		abs[absPath] = content
+	}
+
+	c := newConfig(abs)
+	if _, err := c.load("", nil); err != nil {
+		return err
+	}
+	for _, name := range c.profileNames() {
+		if _, err := newConfig(abs).load(name, nil); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// readNode parses a config file, returning an empty document if it does not exist
+func readNode(file string) (*yaml.Node, error) {
+	var doc yaml.Node
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return &doc, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// JSON is valid YAML
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error reading config file %s: %w", file, err)
+	}
+	return &doc, nil
+}
+
+// encodeNode formats a document for file, as JSON for .json files and as
+// YAML with the file's existing indentation otherwise
+func encodeNode(file string, doc *yaml.Node) ([]byte, error) {
+	if strings.HasSuffix(file, ".json") {
+		var value interface{}
+		if err := doc.Decode(&value); err != nil {
+			return nil, err
+		}
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	}
+
+	// A file whose last key was removed is left empty rather than holding {}
+	if m := root(doc); len(m.Content) == 0 && m.HeadComment == "" && m.FootComment == "" && doc.HeadComment == "" && doc.FootComment == "" {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(detectIndent(file))
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// detectIndent returns the indentation of the first indented line in file, or 2
+func detectIndent(file string) int {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 2
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if n := len(line) - len(trimmed); n > 0 && trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "-") {
+			return n
+		}
+	}
+	return 2
+}
+
+// root returns the top level mapping of a document, creating it if the document is empty
+func root(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	return doc.Content[0]
+}
+
+// findKey returns the key and value nodes at path
+func findKey(doc *yaml.Node, path []string) (*yaml.Node, *yaml.Node, bool) {
+	if len(doc.Content) == 0 {
+		return nil, nil, false
+	}
+	node := doc.Content[0]
+	var keyNode *yaml.Node
+	for _, part := range path {
+		var found bool
+		keyNode, node, found = lookup(node, part)
+		if !found {
+			return nil, nil, false
+		}
+	}
+	return keyNode, node, true
+}
+
+// lookup finds a key in a mapping. Keys match case insensitively, as they do when config is loaded.
+func lookup(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node, bool) {
+	if mapping.Kind != yaml.MappingNode {
+		return nil, nil, false
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.EqualFold(mapping.Content[i].Value, key) {
+			return mapping.Content[i], mapping.Content[i+1], true
+		}
+	}
+	return nil, nil, false
+}
+
+// removeKey deletes path from mapping and reports whether it was there
+func removeKey(mapping *yaml.Node, path []string) bool {
+	if mapping.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if !strings.EqualFold(mapping.Content[i].Value, path[0]) {
+			continue
+		}
+		if len(path) == 1 {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return true
+		}
+		child := mapping.Content[i+1]
+		if !removeKey(child, path[1:]) {
+			return false
+		}
+		if child.Kind == yaml.MappingNode && len(child.Content) == 0 {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+		}
+		return true
+	}
+	return false
+}
+
+func splitKey(key string) []string {
+	if key == "" {
+		return nil
+	}
+	return strings.Split(key, ".")
+}
+
+// addReplacements adds files being validated to the files found in dir
+func (c *Config) addReplacements(dir string, files []string) []string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return files
+	}
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		if abs, err := filepath.Abs(f); err == nil {
+			seen[abs] = true
+		}
+	}
+	added := false
+	for path := range c.replacement {
+		if filepath.Dir(path) == absDir && !seen[path] && isConfigFile(filepath.Base(path)) {
+			files = append(files, path)
+			added = true
+		}
+	}
+	if added {
+		sort.Slice(files, func(i, j int) bool {
+			return filepath.Base(files[i]) < filepath.Base(files[j])
+		})
+	}
+	return files
+}
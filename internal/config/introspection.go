@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 )
 
 // PrintConfig prints the configuration with optional sources in YAML format
 func (s *ConfigSchema) PrintConfig(includeSources bool, prefix string) {
+	if includeSources && s.profile != "" {
+		fmt.Printf("# profile: %s\n", s.profile)
+	}
 	s.printValue(reflect.ValueOf(*s), "", "", includeSources, 0, prefix)
 	for _, w := range s.warnings {
 		fmt.Println(w)
@@ -17,7 +21,7 @@ func (s *ConfigSchema) PrintConfig(includeSources bool, prefix string) {
 func (s *ConfigSchema) printValue(v reflect.Value, key, fullKey string, includeSources bool, indent int, prefix string) {
 	t := v.Type()
 
-	prefixParts := strings.Split(prefix, ".")
+	prefixParts := strings.Split(strings.ToLower(prefix), ".")
 	prefixNext := ""
 	prefixPart := prefixParts[0]
 	if len(prefixParts) > 0 {
@@ -71,8 +75,19 @@ func (s *ConfigSchema) printValue(v reflect.Value, key, fullKey string, includeS
 			s.printValue(iter.Value(), k, nextFullKey, includeSources, indent, prefixNext)
 		}
 
+	case reflect.Slice:
+		// Lists merged from several layers show where each element came from
+		if origins := s.elementSources(fullKey, v.Len()); includeSources && origins != nil && !isSecretKey(key) {
+			fmt.Printf("%s%s:\n", strings.Repeat("  ", indent), key)
+			for i := 0; i < v.Len(); i++ {
+				fmt.Printf("%s- %v # (%s)\n", strings.Repeat("  ", indent+1), v.Index(i).Interface(), origins[i])
+			}
+			return
+		}
+		fallthrough
+
 	default:
-		if isSecretKey(key) {
+		if isSecretKey(key) || isSecretEnv(fullKey, v) || isSecretAPIKey(fullKey, v) {
 			fmt.Printf("%s%s: [REDACTED]", strings.Repeat("  ", indent), key)
 		} else {
 			fmt.Printf("%s%s: %v", strings.Repeat("  ", indent), key, v.Interface())
@@ -82,20 +97,179 @@ func (s *ConfigSchema) printValue(v reflect.Value, key, fullKey string, includeS
 	}
 }
 
+// ConfigValue is a configuration value with the source that set it
+type ConfigValue struct {
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+
+	// Source of each element of a list merged from several layers
+	Elements []string `json:"elements,omitempty"`
+}
+
+// ConfigMap returns the configuration shown by PrintConfig as nested maps,
+// keyed like the config files, for the structured output formats. With
+// includeSources every value is a ConfigValue.
+func (s *ConfigSchema) ConfigMap(includeSources bool, prefix string) map[string]interface{} {
+	result, _ := s.mapValue(reflect.ValueOf(*s), "", "", includeSources, prefix).(map[string]interface{})
+	if result == nil {
+		result = make(map[string]interface{})
+	}
+	return result
+}
+
+// Get returns the value at a dot separated key, matched case insensitively,
+// as ConfigMap would show it
+func (s *ConfigSchema) Get(key string) (interface{}, bool) {
+	var value interface{} = s.ConfigMap(false, "")
+	for _, part := range strings.Split(key, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		found := false
+		for k, v := range m {
+			if strings.EqualFold(k, part) {
+				value, found = v, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// Warnings returns problems found while loading the configuration
+func (s *ConfigSchema) Warnings() []string {
+	return s.warnings
+}
+
+func (s *ConfigSchema) mapValue(v reflect.Value, key, fullKey string, includeSources bool, prefix string) interface{} {
+	t := v.Type()
+
+	prefixParts := strings.Split(strings.ToLower(prefix), ".")
+	prefixNext := strings.Join(prefixParts[1:], ".")
+	prefixPart := prefixParts[0]
+
+	joinKey := func(k string) string {
+		if fullKey == "" {
+			return k
+		}
+		return fullKey + "." + k
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		result := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !strings.HasPrefix(strings.ToLower(field.Name), prefixPart) {
+				continue
+			}
+			if !field.IsExported() || field.Tag.Get("mapstructure") == "" {
+				continue
+			}
+			fieldValue := v.Field(i)
+			if !fieldValue.IsZero() {
+				tag := field.Tag.Get("mapstructure")
+				result[tag] = s.mapValue(fieldValue, tag, joinKey(tag), includeSources, prefixNext)
+			}
+		}
+		return result
+
+	case reflect.Map:
+		result := make(map[string]interface{})
+		iter := v.MapRange()
+		for iter.Next() {
+			k := iter.Key().String()
+			if !strings.HasPrefix(strings.ToLower(k), prefixPart) {
+				continue
+			}
+			result[k] = s.mapValue(iter.Value(), k, joinKey(k), includeSources, prefixNext)
+		}
+		return result
+
+	default:
+		var value interface{}
+		switch {
+		case isSecretKey(key) || isSecretEnv(fullKey, v) || isSecretAPIKey(fullKey, v):
+			value = "[REDACTED]"
+		case v.Type() == reflect.TypeOf(time.Duration(0)):
+			value = v.Interface().(time.Duration).String()
+		default:
+			value = v.Interface()
+		}
+		if !includeSources {
+			return value
+		}
+		configValue := ConfigValue{Value: value, Source: s.source(fullKey)}
+		if v.Kind() == reflect.Slice && value != "[REDACTED]" {
+			configValue.Elements = s.elementSources(fullKey, v.Len())
+		}
+		return configValue
+	}
+}
+
+// elementSources returns the source of each element of the list at key if
+// they came from more than one layer
+func (s *ConfigSchema) elementSources(key string, length int) []string {
+	origins := s.elements[strings.ToLower(key)]
+	if len(origins) != length || !strings.Contains(joinSources(origins), " + ") {
+		return nil
+	}
+	return origins
+}
+
 func (s *ConfigSchema) printSourceInfo(key string, includeSources bool) {
 	if !includeSources {
 		return
 	}
 
-	if source, ok := s.sources[strings.ToLower(key)]; ok {
-		fmt.Printf(" # (%s)", source)
-	} else {
-		fmt.Printf(" # (default)")
+	fmt.Printf(" # (%s)", s.source(key))
+}
+
+// source returns where key, or the nearest parent of it, was set. Values
+// decoded from a single config value, like an API key string, are only
+// tracked at the parent.
+func (s *ConfigSchema) source(key string) string {
+	key = strings.ToLower(key)
+	for key != "" {
+		if source, ok := s.sources[key]; ok {
+			return source
+		}
+		i := strings.LastIndex(key, ".")
+		if i < 0 {
+			break
+		}
+		key = key[:i]
 	}
+	return "default"
 }
 
 func isSecretKey(key string) bool {
 	return strings.Contains(strings.ToLower(key), "key") ||
 		strings.Contains(strings.ToLower(key), "secret") ||
-		strings.Contains(strings.ToLower(key), "password")
+		strings.Contains(strings.ToLower(key), "password") ||
+		strings.HasSuffix(strings.ToLower(key), "token")
+}
+
+// isSecretEnv reports whether v is a literal MCP server env value. These are
+// usually credentials, so only ${...} references are shown.
+func isSecretEnv(fullKey string, v reflect.Value) bool {
+	parts := strings.Split(strings.ToLower(fullKey), ".")
+	if len(parts) != 4 || parts[0] != "mcpservers" || parts[2] != "env" {
+		return false
+	}
+	return v.Kind() != reflect.String || v.String() != "" && !IsReference(v.String())
+}
+
+// isSecretAPIKey reports whether v is a literal API key or storage DSN,
+// which may hold a password, as with isSecretEnv
+func isSecretAPIKey(fullKey string, v reflect.Value) bool {
+	fullKey = strings.ToLower(fullKey)
+	if !strings.HasSuffix(fullKey, ".apikey.value") && fullKey != "storage.dsn" {
+		return false
+	}
+	return v.Kind() != reflect.String || !IsReference(v.String())
 }
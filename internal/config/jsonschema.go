@@ -0,0 +1,160 @@
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+JSONSchema describes config files for editors, e.g. with the YAML language
+server:
+
+	# yaml-language-server: $schema=/path/to/slop.schema.json
+
+It is generated from ConfigSchema. Validate tags become enums and bounds, but
+not required properties, since a file may set only part of a value that
+another file completes. Keys are matched case insensitively when config is
+loaded, while editors use the names in the schema.
+*/
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// JSONSchema returns a JSON Schema for config files
+func JSONSchema() map[string]interface{} {
+	defs := make(map[string]interface{})
+	schema := structSchema(reflect.TypeOf(ConfigSchema{}), defs)
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "slop configuration"
+	schema["properties"].(map[string]interface{})["profiles"] = map[string]interface{}{
+		"type":                 "object",
+		"description":          "Named sets of values, selected with --profile or SLOP_PROFILE",
+		"additionalProperties": map[string]interface{}{"$ref": "#"},
+	}
+	schema["$defs"] = defs
+	return schema
+}
+
+// mergeSchema describes the _merge directive, see merge.go
+var mergeSchema = map[string]interface{}{
+	"description": "Replace or remove what earlier config layers set",
+	"enum":        []interface{}{string(mergeReplace), string(mergeRemove)},
+}
+
+func structSchema(t reflect.Type, defs map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{mergeKey: mergeSchema}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if !field.IsExported() || name == "" {
+			continue
+		}
+		prop := typeSchema(field.Type, defs)
+		addConstraints(prop, field.Tag.Get("validate"))
+		properties[name] = prop
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+func typeSchema(t reflect.Type, defs map[string]interface{}) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(APIKey{}) {
+		// A string or where to read the key from, see apiKeyHook
+		return map[string]interface{}{
+			"anyOf": []interface{}{
+				map[string]interface{}{"type": "string"},
+				map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"command": map[string]interface{}{"type": "string", "description": "Shell command printing the key"},
+						"file":    map[string]interface{}{"type": "string", "description": "File containing the key"},
+						"env":     map[string]interface{}{"type": "string", "description": "Environment variable holding the key"},
+					},
+					"additionalProperties": false,
+					"maxProperties":        1,
+				},
+			},
+		}
+	}
+	if t == durationType {
+		return map[string]interface{}{
+			"type":        "string",
+			"description": "Duration, e.g. 30s or 2m",
+			"pattern":     `^(\d+(\.\d+)?(ns|us|µs|ms|s|m|h))+$|^0$`,
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		// Named types are defined once, which also allows recursive types like Property
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = map[string]interface{}{}
+			defs[t.Name()] = structSchema(t, defs)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           map[string]interface{}{mergeKey: mergeSchema},
+			"additionalProperties": typeSchema(t.Elem(), defs),
+		}
+	case reflect.Slice:
+		items := typeSchema(t.Elem(), defs)
+		if items["type"] != "string" {
+			// Allow "!replace" as the first element, see merge.go
+			items = map[string]interface{}{
+				"anyOf": []interface{}{items, map[string]interface{}{"const": replaceMarker}},
+			}
+		}
+		return map[string]interface{}{"type": "array", "items": items}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		// interface{} accepts any value
+		return map[string]interface{}{}
+	}
+}
+
+// addConstraints adds the rules in a validate tag that JSON Schema can express
+func addConstraints(schema map[string]interface{}, tag string) {
+	if _, ok := schema["$ref"]; ok || tag == "" {
+		return
+	}
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "oneof":
+			var enum []interface{}
+			for _, v := range strings.Fields(param) {
+				enum = append(enum, v)
+			}
+			schema["enum"] = enum
+		case "gte", "lte":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil || schema["type"] == "string" {
+				continue
+			}
+			if name == "gte" {
+				schema["minimum"] = n
+			} else {
+				schema["maximum"] = n
+			}
+		case "url":
+			schema["format"] = "uri"
+		}
+	}
+}
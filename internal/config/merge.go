@@ -0,0 +1,177 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+/*
+Each config layer is merged over the ones before it: maps are merged key by
+key, lists are concatenated and other values replaced. Layers can change this
+with merge directives.
+
+A _merge key in a map replaces or removes what earlier layers set:
+
+	mcpServers:
+	  github:
+	    _merge: remove  # drop the server from global config
+	  filesystem:
+	    _merge: replace # use only the keys below
+	    command: npx
+
+A list whose first element is "!replace" replaces the list instead of adding
+to it:
+
+	nativeTools:
+	  enabled: ["!replace", read_file]
+
+Environment variables and --set always replace values.
+*/
+
+const (
+	mergeKey      = "_merge"
+	replaceMarker = "!replace"
+)
+
+// Change is a value set by one config layer while loading, see Explain
+type Change struct {
+	Source string      `json:"source"`
+	Key    string      `json:"key"`
+	Action string      `json:"action"` // set, append, replace or remove
+	Value  interface{} `json:"value,omitempty"`
+}
+
+// mergeDirective marks a map with a _merge key in flattened settings
+type mergeDirective string
+
+const (
+	mergeReplace mergeDirective = "replace"
+	mergeRemove  mergeDirective = "remove"
+)
+
+// directive returns the _merge directive in a map, if there is one
+func directive(m map[string]interface{}, key string) (mergeDirective, bool, error) {
+	raw, ok := m[mergeKey]
+	if !ok {
+		return "", false, nil
+	}
+	d := mergeDirective(strings.ToLower(fmt.Sprint(raw)))
+	if d != mergeReplace && d != mergeRemove {
+		return "", false, fmt.Errorf("%s.%s must be %q or %q, got %q", key, mergeKey, mergeReplace, mergeRemove, raw)
+	}
+	return d, true, nil
+}
+
+// applyLayer sets flattened values from one layer. Keys are applied in order
+// so that a directive on a map is applied before the values below it.
+func (c *Config) applyLayer(flat map[string]interface{}, source string) {
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		c.mergeValue(key, flat[key], source)
+	}
+}
+
+// mergeValue sets key from a config layer, following merge directives
+func (c *Config) mergeValue(key string, value interface{}, source string) {
+	switch val := value.(type) {
+	case mergeDirective:
+		c.clear(key)
+		c.record(source, key, string(val), nil)
+		return
+
+	case []interface{}:
+		if len(val) > 0 && val[0] == replaceMarker {
+			items := val[1:]
+			c.setList(key, items, source)
+			c.record(source, key, "replace", items)
+			return
+		}
+		if existing, ok := c.v.Get(key).([]interface{}); ok && len(existing) > 0 {
+			items := append(append([]interface{}{}, existing...), val...)
+			origins := c.elements[key]
+			for len(origins) < len(existing) {
+				origins = append(origins, c.sources[key])
+			}
+			for range val {
+				origins = append(origins, source)
+			}
+			c.v.Set(key, items)
+			c.elements[key] = origins
+			c.sources[key] = joinSources(origins)
+			c.record(source, key, "append", val)
+			return
+		}
+		c.setList(key, val, source)
+		c.record(source, key, "set", val)
+		return
+	}
+
+	c.v.Set(key, value)
+	c.sources[key] = source
+	delete(c.elements, key)
+	c.record(source, key, "set", value)
+}
+
+// setList replaces the list at key
+func (c *Config) setList(key string, items []interface{}, source string) {
+	origins := make([]string, len(items))
+	for i := range origins {
+		origins[i] = source
+	}
+	c.v.Set(key, items)
+	c.elements[key] = origins
+	c.sources[key] = source
+}
+
+// clear removes key and everything below it. Viper cannot unset a key, but
+// an empty map has no keys and decodes to the zero value.
+func (c *Config) clear(key string) {
+	c.v.Set(key, map[string]interface{}{})
+	for k := range c.elements {
+		if k == key || strings.HasPrefix(k, key+".") {
+			delete(c.elements, k)
+		}
+	}
+}
+
+func (c *Config) record(source, key, action string, value interface{}) {
+	c.changes = append(c.changes, Change{Source: source, Key: key, Action: action, Value: value})
+}
+
+// joinSources lists the distinct sources of a list's elements in order
+func joinSources(origins []string) string {
+	var distinct []string
+	seen := make(map[string]bool)
+	for _, origin := range origins {
+		if !seen[origin] {
+			seen[origin] = true
+			distinct = append(distinct, origin)
+		}
+	}
+	return strings.Join(distinct, " + ")
+}
+
+// Explain returns the changes each config layer made to key, the keys below
+// it and the maps containing it, in the order they were applied. Secrets are
+// redacted as they are in PrintConfig.
+func (s *ConfigSchema) Explain(key string) []Change {
+	key = strings.ToLower(key)
+	var changes []Change
+	for _, change := range s.changes {
+		if change.Key != key && !strings.HasPrefix(change.Key, key+".") && !strings.HasPrefix(key, change.Key+".") {
+			continue
+		}
+		name := change.Key[strings.LastIndex(change.Key, ".")+1:]
+		if change.Value != nil && (isSecretKey(name) || isSecretEnv(change.Key, reflect.ValueOf(change.Value)) || isSecretAPIKey(change.Key, reflect.ValueOf(change.Value))) {
+			change.Value = "[REDACTED]"
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+Models can be referred to by an alias wherever a model name is expected:
+activeModel, commandModels, internal.model and --model.
+
+	modelAliases:
+	  fast: gemini
+	  smart: claude
+	commandModels:
+	  summary: fast
+
+A model can extend another, inheriting its settings and overriding some. Maps
+such as tools are merged, other values including lists are replaced:
+
+	models:
+	  claude-creative:
+	    extends: claude
+	    temperature: 1
+*/
+
+// ResolveModel returns the model with the given name or alias. Names are
+// matched case insensitively, as config keys are.
+func (s *ConfigSchema) ResolveModel(name string) (string, Model, error) {
+	name = strings.ToLower(name)
+	var chain []string
+	for {
+		if model, ok := s.Models[name]; ok {
+			return name, model, nil
+		}
+		target, ok := s.ModelAliases[name]
+		if !ok {
+			if len(chain) > 0 {
+				return "", Model{}, fmt.Errorf("alias %s refers to model %q which is not configured, models: %v", chain[len(chain)-1], name, s.modelNames())
+			}
+			return "", Model{}, fmt.Errorf("model %q not found, models: %v, aliases: %v", name, s.modelNames(), s.aliasNames())
+		}
+		chain = append(chain, name)
+		for _, seen := range chain[:len(chain)-1] {
+			if seen == name {
+				return "", Model{}, fmt.Errorf("model aliases form a cycle: %s", strings.Join(chain, " -> "))
+			}
+		}
+		name = strings.ToLower(target)
+	}
+}
+
+// MsgModel returns the name or alias of the model for msg commands
+func (s *ConfigSchema) MsgModel() string {
+	if s.CommandModels.Msg != "" {
+		return s.CommandModels.Msg
+	}
+	return s.ActiveModel
+}
+
+// SummaryModel returns the name or alias of the model for thread summaries
+func (s *ConfigSchema) SummaryModel() string {
+	if s.CommandModels.Summary != "" {
+		return s.CommandModels.Summary
+	}
+	return s.Internal.Model
+}
+
+// CompareModel returns the name or alias of the model for comparing responses
+func (s *ConfigSchema) CompareModel() string {
+	if s.CommandModels.Compare != "" {
+		return s.CommandModels.Compare
+	}
+	return s.ActiveModel
+}
+
+// CommitModel returns the name or alias of the model for commit messages
+func (s *ConfigSchema) CommitModel() string {
+	if s.CommandModels.Commit != "" {
+		return s.CommandModels.Commit
+	}
+	return s.ActiveModel
+}
+
+func (s *ConfigSchema) modelNames() []string {
+	names := make([]string, 0, len(s.Models))
+	for name := range s.Models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *ConfigSchema) aliasNames() []string {
+	names := make([]string, 0, len(s.ModelAliases))
+	for name := range s.ModelAliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateModels checks that aliases do not shadow models and that every
+// model reference resolves
+func (c *Config) validateModels(schema *ConfigSchema) error {
+	for _, alias := range schema.aliasNames() {
+		key := "modelAliases." + alias
+		if _, ok := schema.Models[alias]; ok {
+			return fmt.Errorf("%s: alias has the same name as a model%s", key, c.sourceSuffix(key))
+		}
+		if _, _, err := schema.ResolveModel(alias); err != nil {
+			return fmt.Errorf("%s: %w%s", key, err, c.sourceSuffix(key))
+		}
+	}
+
+	refs := []struct{ key, name string }{
+		{"activeModel", schema.ActiveModel},
+		{"commandModels.msg", schema.CommandModels.Msg},
+		{"commandModels.summary", schema.CommandModels.Summary},
+		{"commandModels.compare", schema.CommandModels.Compare},
+		{"commandModels.commit", schema.CommandModels.Commit},
+		{"internal.model", schema.Internal.Model},
+	}
+	for _, ref := range refs {
+		if ref.name == "" {
+			continue
+		}
+		if _, _, err := schema.ResolveModel(ref.name); err != nil {
+			return fmt.Errorf("%s: %w%s", ref.key, err, c.sourceSuffix(ref.key))
+		}
+	}
+	return nil
+}
+
+// resolveExtends merges each model that extends another over the settings it
+// inherits, before config is decoded
+func (c *Config) resolveExtends() error {
+	models, _ := c.v.Get("models").(map[string]interface{})
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resolved := make(map[string]map[string]interface{})
+	var resolve func(name string, chain []string) (map[string]interface{}, error)
+	resolve = func(name string, chain []string) (map[string]interface{}, error) {
+		if model, ok := resolved[name]; ok {
+			return model, nil
+		}
+		model, _ := models[name].(map[string]interface{})
+		parentName, _ := model["extends"].(string)
+		if parentName == "" {
+			resolved[name] = model
+			return model, nil
+		}
+
+		key := "models." + name + ".extends"
+		parentName = strings.ToLower(parentName)
+		chain = append(chain, name)
+		for _, seen := range chain {
+			if seen == parentName {
+				return nil, fmt.Errorf("%s: models extend each other in a cycle: %s -> %s%s",
+					key, strings.Join(chain, " -> "), parentName, c.sourceSuffix(key))
+			}
+		}
+		if _, ok := models[parentName]; !ok {
+			return nil, fmt.Errorf("%s: model %q not found%s", key, parentName, c.sourceSuffix(key))
+		}
+		parent, err := resolve(parentName, chain)
+		if err != nil {
+			return nil, err
+		}
+
+		merged := mergeMaps(parent, model)
+		c.inheritSources("models."+parentName, "models."+name, parent, model, parentName)
+		resolved[name] = merged
+		return merged, nil
+	}
+
+	for _, name := range names {
+		model, err := resolve(name, nil)
+		if err != nil {
+			return err
+		}
+		if extends, _ := model["extends"].(string); extends != "" {
+			c.v.Set("models."+name, model)
+		}
+	}
+	return nil
+}
+
+// mergeMaps returns base with override merged over it, recursing into maps
+func mergeMaps(base, override map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(base)+len(override))
+	for k, v := range base {
+		result[k] = v
+	}
+	for k, v := range override {
+		baseMap, baseOk := result[k].(map[string]interface{})
+		overrideMap, overrideOk := v.(map[string]interface{})
+		if baseOk && overrideOk {
+			result[k] = mergeMaps(baseMap, overrideMap)
+		} else {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// inheritSources records the source of values a model inherits from parent
+func (c *Config) inheritSources(parentKey, key string, parent, model map[string]interface{}, parentName string) {
+	for k, v := range parent {
+		if k == "extends" {
+			continue
+		}
+		own, set := model[k]
+		parentMap, isMap := v.(map[string]interface{})
+		if set {
+			if ownMap, ok := own.(map[string]interface{}); ok && isMap {
+				c.inheritSources(parentKey+"."+k, key+"."+k, parentMap, ownMap, parentName)
+			}
+			continue
+		}
+		source, ok := c.sources[parentKey+"."+k]
+		if !ok {
+			source = "default"
+		}
+		c.sources[key+"."+k] = fmt.Sprintf("%s, inherited from %s", source, parentName)
+		if isMap {
+			c.inheritSources(parentKey+"."+k, key+"."+k, parentMap, map[string]interface{}{}, parentName)
+		}
+	}
+}
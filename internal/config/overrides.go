@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+/*
+Any key can be overridden by an environment variable named SLOP_ followed by
+the key in upper case, with __ between levels:
+
+	SLOP_ACTIVEMODEL=claude
+	SLOP_MODELS__CLAUDE__TEMPERATURE=0.2
+
+or with the --set flag, which takes precedence over the environment:
+
+	slop --set agent.maxSteps=50 --set activeModel=claude msg send ...
+
+Values are parsed as YAML, so 0.2 is a number and [a, b] is a list.
+
+String values in config files may reference environment variables with
+${VAR} or ${env:VAR}, which are replaced when config is loaded. Values that
+are passed to processes (MCP server args, env and cwd, and shell commands),
+API keys and the storage DSN are left as written and resolved when used, see
+secrets.go.
+*/
+
+const envPrefix = "SLOP_"
+
+// Variables with the SLOP_ prefix that are not config keys
+var reservedEnv = map[string]bool{
+	"SLOP_PROFILE": true,
+	"SLOP_EVENT":   true, // Set for hooks
+}
+
+// applyEnv overrides keys from SLOP_* variables in environ. Variables that do
+// not name a config key are reported as warnings.
+func (c *Config) applyEnv(environ []string) {
+	sort.Strings(environ)
+	for _, entry := range environ {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(name, envPrefix) || reservedEnv[name] {
+			continue
+		}
+		key := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(name, envPrefix), "__", "."))
+		if !knownKey(key) {
+			c.warnings = append(c.warnings, fmt.Sprintf("Warning: %s does not match a config key", name))
+			continue
+		}
+		c.setOverride(key, value, "env "+name)
+	}
+}
+
+// applySets overrides keys from key=value pairs given with --set
+func (c *Config) applySets(sets []string) error {
+	for _, set := range sets {
+		key, value, ok := strings.Cut(set, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("invalid --set %q, expected key=value", set)
+		}
+		if !knownKey(key) {
+			return fmt.Errorf("invalid --set %q: %s is not a config key", set, key)
+		}
+		c.setOverride(strings.ToLower(key), value, "--set")
+	}
+	return nil
+}
+
+// setOverride replaces the value at key, lists included
+func (c *Config) setOverride(key, raw, source string) {
+	value := parseValue(raw)
+	if items, ok := value.([]interface{}); ok {
+		if len(items) > 0 && items[0] == replaceMarker {
+			items = items[1:]
+		}
+		c.setList(key, items, source)
+		c.record(source, key, "set", items)
+		return
+	}
+	c.mergeValue(key, value, source)
+}
+
+// parseValue parses an override as YAML, treating anything that is not a
+// plain value as a string
+func parseValue(raw string) interface{} {
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(raw), &value); err != nil || value == nil {
+		return raw
+	}
+	if _, ok := value.(map[string]interface{}); ok {
+		return raw
+	}
+	return value
+}
+
+// knownKey reports whether a dot separated key names a field in ConfigSchema.
+// Any key is accepted under maps, and below free-form values.
+func knownKey(key string) bool {
+	t := reflect.TypeOf(ConfigSchema{})
+	for _, part := range strings.Split(key, ".") {
+		switch t.Kind() {
+		case reflect.Struct:
+			found := false
+			for i := 0; i < t.NumField(); i++ {
+				field := t.Field(i)
+				if tag := field.Tag.Get("mapstructure"); tag != "" && strings.EqualFold(tag, part) {
+					t, found = field.Type, true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Interface:
+			return true
+		default:
+			return false
+		}
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+	return true
+}
+
+// interpolate replaces ${VAR} references to environment variables in string
+// values, except those that are resolved when a process is launched
+func (c *Config) interpolate() {
+	for _, key := range c.v.AllKeys() {
+		if resolvedAtLaunch(key) {
+			continue
+		}
+		var used []string
+		switch value := c.v.Get(key).(type) {
+		case string:
+			resolved, vars := interpolateEnv(value)
+			if resolved == value {
+				continue
+			}
+			c.v.Set(key, resolved)
+			used = vars
+		case []interface{}:
+			items := make([]interface{}, len(value))
+			changed := false
+			for i, item := range value {
+				items[i] = item
+				if s, ok := item.(string); ok {
+					resolved, vars := interpolateEnv(s)
+					items[i] = resolved
+					used = append(used, vars...)
+					changed = changed || resolved != s
+				}
+			}
+			if !changed {
+				continue
+			}
+			c.v.Set(key, items)
+		default:
+			continue
+		}
+
+		if len(used) == 0 {
+			continue
+		}
+		source, ok := c.sources[key]
+		if !ok {
+			source = "default"
+		}
+		c.sources[key] = fmt.Sprintf("%s, with $%s", source, strings.Join(used, ", $"))
+	}
+}
+
+// resolvedAtLaunch reports whether a key holds a value passed to a process
+// or an API key, whose ${...} references are resolved when it is used
+func resolvedAtLaunch(key string) bool {
+	parts := strings.Split(key, ".")
+	if parts[len(parts)-1] == "command" {
+		return true
+	}
+	if len(parts) >= 3 && parts[0] == "models" && parts[2] == "apikey" || key == "storage.dsn" {
+		return true
+	}
+	return len(parts) >= 3 && parts[0] == "mcpservers" &&
+		(parts[2] == "args" || parts[2] == "env" || parts[2] == "cwd")
+}
+
+// interpolateEnv replaces ${VAR} and ${env:VAR} in value, keeping other
+// references and unescaping $${...}. It returns the variables used.
+func interpolateEnv(value string) (string, []string) {
+	var used []string
+	result := referencePattern.ReplaceAllStringFunc(value, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		ref := match[2 : len(match)-1]
+		kind, name, ok := strings.Cut(ref, ":")
+		if !ok {
+			name = kind
+		} else if kind != "env" {
+			return match
+		}
+		used = append(used, name)
+		return os.Getenv(name)
+	})
+	return result, used
+}
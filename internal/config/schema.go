@@ -1,34 +1,65 @@
 package config
 
+import (
+	"fmt"
+	"time"
+)
+
 // LLM presets
 type Model struct {
-	Provider    string          `mapstructure:"provider"`
-	Name        string          `mapstructure:"name"`
-	MaxTokens   int             `mapstructure:"MaxTokens"`
-	Temperature float64         `mapstructure:"temperature"`
-	Tools       map[string]Tool `mapstructure:"tools"`
+	Provider    string          `mapstructure:"provider" validate:"required,oneof=openai anthropic googleai"`
+	Name        string          `mapstructure:"name" validate:"required"`
+	MaxTokens   int             `mapstructure:"maxTokens" validate:"gte=0"`
+	Temperature float64         `mapstructure:"temperature" validate:"gte=0,lte=2"`
+	Tools       map[string]Tool `mapstructure:"tools" validate:"dive"`
+	ActiveTools []string        `mapstructure:"activeTools"`                 // Globs selecting tools to offer, e.g. [filesystem__*, !filesystem__write_file]
+	InputCost   float64         `mapstructure:"inputCost" validate:"gte=0"`  // USD per million input tokens, used for costBudget
+	OutputCost  float64         `mapstructure:"outputCost" validate:"gte=0"` // USD per million output tokens, used for costBudget
+	APIKey      APIKey          `mapstructure:"apiKey"`                      // Defaults to the key stored with slop auth set
+	Extends     string          `mapstructure:"extends"`                     // Model whose settings this one inherits, see models.go
+}
+
+// Cost returns the price in USD of a model call
+func (m Model) Cost(inputTokens, outputTokens int) float64 {
+	return (float64(inputTokens)*m.InputCost + float64(outputTokens)*m.OutputCost) / 1_000_000
 }
 
 type Tool struct {
 	Name        string     `mapstructure:"name"`
 	Description string     `mapstructure:"description"`
 	Parameters  Parameters `mapstructure:"parameters"`
+	// Raw JSON Schema for the arguments, as advertised by MCP servers. Takes
+	// precedence over Parameters when set. In config, property names are
+	// lower cased like every other key.
+	InputSchema map[string]interface{} `mapstructure:"inputSchema"`
+
+	// Executors for tools declared in config. Set at most one.
+	Command string `mapstructure:"command" validate:"excluded_with=URL"` // Shell command, receives arguments as JSON on stdin
+	URL     string `mapstructure:"url" validate:"omitempty,url"`         // Localhost URL, receives arguments as a JSON POST body
 }
 
 type Parameters struct {
-	Type       string              `mapstructure:"type"`
-	Properties map[string]Property `mapstructure:"properties"`
+	Type       string              `mapstructure:"type" validate:"omitempty,oneof=object"`
+	Properties map[string]Property `mapstructure:"properties" validate:"dive"`
 	Required   []string            `mapstructure:"required"`
 }
 
 type Property struct {
-	Type        string              `mapstructure:"type"`
+	Type        string              `mapstructure:"type" validate:"omitempty,oneof=string number integer boolean array object null"`
 	Description string              `mapstructure:"description"`
 	Enum        []string            `mapstructure:"enum"`
-	Items       *Property           `mapstructure:"items"`      // For array types
-	Properties  map[string]Property `mapstructure:"properties"` // For object types
-	Required    []string            `mapstructure:"required"`   // For object types
-	Default     interface{}         `mapstructure:"default"`    // For properties with default values
+	Items       *Property           `mapstructure:"items"`                      // For array types
+	Properties  map[string]Property `mapstructure:"properties" validate:"dive"` // For object types
+	Required    []string            `mapstructure:"required"`                   // For object types
+	Default     interface{}         `mapstructure:"default"`                    // For properties with default values
+}
+
+// Models used by commands instead of activeModel, by name or alias
+type CommandModels struct {
+	Msg     string `mapstructure:"msg"`     // msg send and edit
+	Summary string `mapstructure:"summary"` // thread summary, defaults to internal.model
+	Compare string `mapstructure:"compare"` // Comparing responses
+	Commit  string `mapstructure:"commit"`  // Writing commit messages
 }
 
 // Internal configuration settings
@@ -38,33 +69,95 @@ type Internal struct {
 }
 
 // MCP
+// Args, Env values and Cwd may contain ${...} references, see secrets.go
 type MCPServer struct {
-	Command string            `mapstructure:"command"`
+	Command string            `mapstructure:"command" validate:"required"`
 	Args    []string          `mapstructure:"args"`
 	Env     map[string]string `mapstructure:"env"`
+	Cwd     string            `mapstructure:"cwd"`
+}
+
+// Tools implemented by slop itself. None are offered unless enabled, and file
+// tools also need at least one root.
+type NativeTools struct {
+	Enabled         []string `mapstructure:"enabled"`         // Built-in tools to offer to the model
+	Roots           []string `mapstructure:"roots"`           // Directories file tools may access
+	AllowedCommands []string `mapstructure:"allowedCommands"` // Executables run_command may run
 }
 
 // "Agent"
+// Limits apply to each message sent, across every model and tool call it triggers. 0 disables a limit.
 type Agent struct {
-	AutoApproveFunctions bool `mapstructure:"autoApproveFunctions"`
+	AutoApproveFunctions bool          `mapstructure:"autoApproveFunctions"`
+	MaxSteps             int           `mapstructure:"maxSteps" validate:"gte=0"`    // Model calls
+	ToolTimeout          time.Duration `mapstructure:"toolTimeout" validate:"gte=0"` // Per tool call, e.g. 2m
+	Timeout              time.Duration `mapstructure:"timeout" validate:"gte=0"`     // Wall clock, e.g. 10m
+	TokenBudget          int           `mapstructure:"tokenBudget" validate:"gte=0"` // Input and output tokens
+	CostBudget           float64       `mapstructure:"costBudget" validate:"gte=0"`  // USD, requires inputCost and outputCost on the model
+}
+
+// Hooks run shell commands on events, see internal/hooks
+type Hooks struct {
+	BeforeSend     []Hook `mapstructure:"beforeSend" validate:"dive"`
+	AfterSend      []Hook `mapstructure:"afterSend" validate:"dive"`
+	BeforeToolCall []Hook `mapstructure:"beforeToolCall" validate:"dive"`
+	AfterToolCall  []Hook `mapstructure:"afterToolCall" validate:"dive"`
+	ThreadCreate   []Hook `mapstructure:"threadCreate" validate:"dive"`
+	ThreadDelete   []Hook `mapstructure:"threadDelete" validate:"dive"`
+}
+
+type Hook struct {
+	Command string        `mapstructure:"command" validate:"required"` // Run with sh -c
+	Match   string        `mapstructure:"match"`                       // Tool events only: glob the tool name must match
+	Timeout time.Duration `mapstructure:"timeout" validate:"gte=0"`    // Defaults to 30s
+}
+
+// Database for threads, see internal/repository/storage
+type Storage struct {
+	Driver string `mapstructure:"driver" validate:"omitempty,oneof=sqlite postgres"` // Defaults to sqlite
+	// Connection string, e.g. postgres://user@host:5432/slop. It may contain
+	// ${...} references, see secrets.go. SQLite defaults to dbPath.
+	DSN string `mapstructure:"dsn"`
+}
+
+// ResolveDSN returns the DSN with references resolved
+func (s Storage) ResolveDSN() (string, error) {
+	dsn, err := ResolveReferences(s.DSN)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve storage.dsn: %w", err)
+	}
+	return dsn, nil
 }
 
 // Logs
 type Log struct {
-	LogLevel string `mapstructure:"logLevel"`
+	LogLevel string `mapstructure:"logLevel" validate:"omitempty,oneof=DEBUG INFO WARN ERROR"`
 	LogFile  string `mapstructure:"logFile"`
 }
 
 type ConfigSchema struct {
-	Models      map[string]Model     `mapstructure:"models"`
-	ActiveModel string               `mapstructure:"activeModel"`
-	DBPath      string               `mapstructure:"dbPath"`
-	Internal    Internal             `mapstructure:"internal"`
-	MCPServers  map[string]MCPServer `mapstructure:"mcpServers"`
-	Agent       Agent                `mapstructure:"agent"`
-	Log         Log                  `mapstructure:"log"`
+	Models        map[string]Model     `mapstructure:"models" validate:"dive"`
+	ActiveModel   string               `mapstructure:"activeModel"`
+	ModelAliases  map[string]string    `mapstructure:"modelAliases"` // Other names for models, e.g. fast: gemini
+	CommandModels CommandModels        `mapstructure:"commandModels"`
+	DBPath        string               `mapstructure:"dbPath"` // Relative to the file that sets it
+	Storage       Storage              `mapstructure:"storage"`
+	Internal      Internal             `mapstructure:"internal"`
+	MCPServers    map[string]MCPServer `mapstructure:"mcpServers" validate:"dive"`
+	NativeTools   NativeTools          `mapstructure:"nativeTools"`
+	Agent         Agent                `mapstructure:"agent"`
+	Hooks         Hooks                `mapstructure:"hooks"`
+	Log           Log                  `mapstructure:"log"`
 
 	// Internal fields for printing
 	sources  map[string]string
+	elements map[string][]string
 	warnings []string
+	profile  string
+	changes  []Change
+}
+
+// Profile returns the name of the selected profile, if any
+func (s *ConfigSchema) Profile() string {
+	return s.profile
 }
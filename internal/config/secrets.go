@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+/*
+Values used to launch processes (such as MCPServer env and args) may contain
+references that are resolved when the value is used rather than when config
+is loaded, so that secrets are never stored in or printed from the config:
+
+	${VAR} or ${env:VAR}   value of an environment variable
+	${file:path}           contents of a file, with surrounding whitespace trimmed
+	${cmd:command}         output of a shell command, e.g. ${cmd:pass show github}
+
+Use $${...} to write a literal ${...}.
+*/
+
+var referencePattern = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+// ResolveReferences replaces every ${...} reference in value
+func ResolveReferences(value string) (string, error) {
+	var resolveErr error
+	result := referencePattern.ReplaceAllStringFunc(value, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		if resolveErr != nil {
+			return ""
+		}
+		resolved, err := resolveReference(match[2 : len(match)-1])
+		if err != nil {
+			resolveErr = err
+			return ""
+		}
+		return resolved
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+	return result, nil
+}
+
+func resolveReference(ref string) (string, error) {
+	kind, arg, ok := strings.Cut(ref, ":")
+	if !ok {
+		return os.Getenv(ref), nil
+	}
+
+	switch kind {
+	case "env":
+		return os.Getenv(arg), nil
+	case "file":
+		data, err := os.ReadFile(ExpandHome(arg))
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file %s: %w", arg, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case "cmd":
+		out, err := exec.Command("sh", "-c", arg).Output()
+		if err != nil {
+			return "", fmt.Errorf("secret command %q failed: %w", arg, err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	default:
+		return "", fmt.Errorf("unknown reference type %q in ${%s}", kind, ref)
+	}
+}
+
+// APIKey is a model's API key. In config it is either a string, which may
+// contain ${...} references, or where to read the key from:
+//
+//	apiKey: { command: pass show openai }
+//	apiKey: { file: ~/.secrets/openai }
+//	apiKey: { env: WORK_OPENAI_KEY }
+//
+// Keys are resolved when a client for the model is created.
+type APIKey struct {
+	Value   string `mapstructure:"value" validate:"excluded_with=Command File Env"`
+	Command string `mapstructure:"command" validate:"excluded_with=File Env"` // Shell command printing the key
+	File    string `mapstructure:"file" validate:"excluded_with=Env"`
+	Env     string `mapstructure:"env"`
+}
+
+// IsSet reports whether a key or a way to read one is configured
+func (k APIKey) IsSet() bool {
+	return k != APIKey{}
+}
+
+// Resolve returns the key
+func (k APIKey) Resolve() (string, error) {
+	var key string
+	var err error
+	switch {
+	case k.Command != "":
+		key, err = resolveReference("cmd:" + k.Command)
+	case k.File != "":
+		key, err = resolveReference("file:" + k.File)
+	case k.Env != "":
+		key = os.Getenv(k.Env)
+		if key == "" {
+			err = fmt.Errorf("environment variable %s for the API key is not set", k.Env)
+		}
+	default:
+		key, err = ResolveReferences(k.Value)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read API key: %w", err)
+	}
+	return key, nil
+}
+
+// apiKeyHook decodes a string as an APIKey value
+func apiKeyHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != reflect.TypeOf(APIKey{}) || from.Kind() != reflect.String {
+		return data, nil
+	}
+	return APIKey{Value: data.(string)}, nil
+}
+
+// IsReference reports whether value consists of a single ${...} reference
+func IsReference(value string) bool {
+	loc := referencePattern.FindStringIndex(value)
+	return loc != nil && loc[0] == 0 && loc[1] == len(value) && !strings.HasPrefix(value, "$$")
+}
+
+// ExpandHome replaces a leading ~ with the user's home directory
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JSONSchema returns the tool's argument schema as JSON Schema, using the raw
+// InputSchema when there is one and converting Parameters otherwise
+func (t Tool) JSONSchema() map[string]interface{} {
+	if t.InputSchema != nil {
+		return t.InputSchema
+	}
+
+	schemaType := t.Parameters.Type
+	if schemaType == "" {
+		schemaType = "object"
+	}
+	schema := map[string]interface{}{
+		"type": schemaType,
+	}
+	if t.Parameters.Properties != nil {
+		properties := make(map[string]interface{}, len(t.Parameters.Properties))
+		for name, prop := range t.Parameters.Properties {
+			properties[name] = prop.JSONSchema()
+		}
+		schema["properties"] = properties
+		// Parameters has always rejected unknown arguments
+		schema["additionalProperties"] = false
+	}
+	if len(t.Parameters.Required) > 0 {
+		schema["required"] = t.Parameters.Required
+	}
+	return schema
+}
+
+// JSONSchema converts a property to JSON Schema
+func (p Property) JSONSchema() map[string]interface{} {
+	schema := make(map[string]interface{})
+	if p.Type != "" {
+		schema["type"] = p.Type
+	}
+	if p.Description != "" {
+		schema["description"] = p.Description
+	}
+	if len(p.Enum) > 0 {
+		schema["enum"] = p.Enum
+	}
+	if p.Items != nil {
+		schema["items"] = p.Items.JSONSchema()
+	}
+	if p.Properties != nil {
+		properties := make(map[string]interface{}, len(p.Properties))
+		for name, prop := range p.Properties {
+			properties[name] = prop.JSONSchema()
+		}
+		schema["properties"] = properties
+	}
+	if len(p.Required) > 0 {
+		schema["required"] = p.Required
+	}
+	if p.Default != nil {
+		schema["default"] = p.Default
+	}
+	return schema
+}
+
+// schemaKeywords are the JSON Schema keywords containing upper case letters,
+// by their lower case form
+var schemaKeywords = map[string]string{}
+
+func init() {
+	for _, keyword := range []string{
+		"additionalProperties", "patternProperties", "propertyNames",
+		"unevaluatedProperties", "unevaluatedItems", "prefixItems",
+		"minLength", "maxLength", "minItems", "maxItems", "uniqueItems",
+		"minProperties", "maxProperties", "minContains", "maxContains",
+		"multipleOf", "exclusiveMinimum", "exclusiveMaximum",
+		"dependentRequired", "dependentSchemas",
+		"contentMediaType", "contentEncoding", "contentSchema",
+		"readOnly", "writeOnly", "$dynamicRef", "$dynamicAnchor",
+		"allOf", "anyOf", "oneOf",
+	} {
+		schemaKeywords[strings.ToLower(keyword)] = keyword
+	}
+}
+
+// Keywords whose values are schemas, maps of names to schemas or lists of schemas
+var (
+	subschemaKeywords = []string{
+		"items", "additionalProperties", "unevaluatedProperties", "unevaluatedItems",
+		"propertyNames", "contains", "not", "if", "then", "else", "contentSchema",
+	}
+	schemaMapKeywords  = []string{"properties", "patternProperties", "$defs", "definitions", "dependentSchemas"}
+	schemaListKeywords = []string{"allOf", "anyOf", "oneOf", "prefixItems"}
+)
+
+// restoreKeywords restores the case of the keywords in a schema declared in
+// config. Config keys are lower cased when loaded, which would turn
+// minLength into minlength and hide it from validation and from the model.
+// Property names cannot be restored and stay lower case.
+func restoreKeywords(schema interface{}) interface{} {
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		return schema
+	}
+
+	restored := make(map[string]interface{}, len(s))
+	for k, v := range s {
+		if keyword, ok := schemaKeywords[k]; ok {
+			k = keyword
+		}
+		restored[k] = v
+	}
+
+	for _, keyword := range subschemaKeywords {
+		if v, ok := restored[keyword]; ok {
+			restored[keyword] = restoreKeywords(v)
+		}
+	}
+	// items is a list of schemas before draft 2020-12
+	if items, ok := restored["items"].([]interface{}); ok {
+		restored["items"] = restoreList(items)
+	}
+	for _, keyword := range schemaMapKeywords {
+		if m, ok := restored[keyword].(map[string]interface{}); ok {
+			schemas := make(map[string]interface{}, len(m))
+			for name, v := range m {
+				schemas[name] = restoreKeywords(v)
+			}
+			restored[keyword] = schemas
+		}
+	}
+	for _, keyword := range schemaListKeywords {
+		if list, ok := restored[keyword].([]interface{}); ok {
+			restored[keyword] = restoreList(list)
+		}
+	}
+	return restored
+}
+
+func restoreList(list []interface{}) []interface{} {
+	restored := make([]interface{}, len(list))
+	for i, v := range list {
+		restored[i] = restoreKeywords(v)
+	}
+	return restored
+}
+
+// checkPropertyNames rejects required properties that only match a property
+// once lower cased, since property names in config are lower cased too
+func checkPropertyNames(schema interface{}) error {
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	properties, _ := s["properties"].(map[string]interface{})
+	for _, name := range stringValues(s["required"]) {
+		if _, ok := properties[name]; ok {
+			continue
+		}
+		if _, ok := properties[strings.ToLower(name)]; ok {
+			return fmt.Errorf("required property %q must be written in lower case, config keys are case-insensitive", name)
+		}
+	}
+
+	for _, keyword := range subschemaKeywords {
+		if err := checkPropertyNames(s[keyword]); err != nil {
+			return err
+		}
+	}
+	for _, keyword := range schemaMapKeywords {
+		m, _ := s[keyword].(map[string]interface{})
+		for _, v := range m {
+			if err := checkPropertyNames(v); err != nil {
+				return err
+			}
+		}
+	}
+	for _, keyword := range append(schemaListKeywords, "items") {
+		list, _ := s[keyword].([]interface{})
+		for _, v := range list {
+			if err := checkPropertyNames(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func stringValues(v interface{}) []string {
+	var values []string
+	switch list := v.(type) {
+	case []string:
+		values = list
+	case []interface{}:
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+// restoreToolSchemas restores the keywords of tool input schemas declared
+// in config, see restoreKeywords
+func (c *Config) restoreToolSchemas(schema *ConfigSchema) error {
+	for modelName, model := range schema.Models {
+		for toolName, tool := range model.Tools {
+			if tool.InputSchema == nil {
+				continue
+			}
+			tool.InputSchema = restoreKeywords(tool.InputSchema).(map[string]interface{})
+			if err := checkPropertyNames(tool.InputSchema); err != nil {
+				key := fmt.Sprintf("models.%s.tools.%s.inputSchema", modelName, toolName)
+				return fmt.Errorf("%s: %w%s", key, err, c.sourceSuffix(key))
+			}
+			model.Tools[toolName] = tool
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRestoreKeywords(t *testing.T) {
+	// As decoded by viper, with every key lower cased
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"additionalproperties": false,
+		"properties": map[string]interface{}{
+			"minlength": map[string]interface{}{"type": "string", "minlength": 3},
+			"tags": map[string]interface{}{
+				"type":     "array",
+				"maxitems": 2,
+				"items":    map[string]interface{}{"maxlength": 4},
+			},
+		},
+		"anyof": []interface{}{map[string]interface{}{"required": []interface{}{"tags"}}},
+		"oneOf": []interface{}{map[string]interface{}{"multipleof": 2}},
+		"$defs": map[string]interface{}{
+			"uniqueitems": map[string]interface{}{"uniqueitems": true},
+		},
+	}
+	want := map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			// Property names are left alone
+			"minlength": map[string]interface{}{"type": "string", "minLength": 3},
+			"tags": map[string]interface{}{
+				"type":     "array",
+				"maxItems": 2,
+				"items":    map[string]interface{}{"maxLength": 4},
+			},
+		},
+		"anyOf": []interface{}{map[string]interface{}{"required": []interface{}{"tags"}}},
+		"oneOf": []interface{}{map[string]interface{}{"multipleOf": 2}},
+		"$defs": map[string]interface{}{
+			"uniqueitems": map[string]interface{}{"uniqueItems": true},
+		},
+	}
+	if got := restoreKeywords(schema); !reflect.DeepEqual(got, want) {
+		t.Errorf("restoreKeywords() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckPropertyNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema map[string]interface{}
+		want   string
+	}{
+		{
+			"lower case",
+			map[string]interface{}{
+				"properties": map[string]interface{}{"path": map[string]interface{}{}},
+				"required":   []interface{}{"path"},
+			},
+			"",
+		},
+		{
+			"camel case",
+			map[string]interface{}{
+				"properties": map[string]interface{}{"filepath": map[string]interface{}{}},
+				"required":   []interface{}{"filePath"},
+			},
+			`required property "filePath" must be written in lower case`,
+		},
+		{
+			"nested",
+			map[string]interface{}{
+				"properties": map[string]interface{}{
+					"options": map[string]interface{}{
+						"properties": map[string]interface{}{"maxdepth": map[string]interface{}{}},
+						"required":   []interface{}{"maxDepth"},
+					},
+				},
+			},
+			`required property "maxDepth"`,
+		},
+		{
+			"missing property",
+			map[string]interface{}{"required": []interface{}{"Other"}},
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPropertyNames(tt.schema)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("checkPropertyNames() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("checkPropertyNames() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
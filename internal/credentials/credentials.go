@@ -0,0 +1,369 @@
+// Package credentials stores provider API keys encrypted in the global config
+// directory.
+//
+// Each key is encrypted with AES-256-GCM. The encryption key is either random
+// and kept in a key file next to the store, readable only by the user, or
+// derived from a passphrase with scrypt. Provider names are stored in the
+// clear so that keys can be listed without decrypting them.
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/slop/internal/config"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	fileName    = "credentials.json"
+	keyFileName = "credentials.key"
+
+	// PassphraseEnv holds the passphrase for stores that use one
+	PassphraseEnv = "SLOP_PASSPHRASE"
+
+	kdfKeyFile = "keyfile"
+	kdfScrypt  = "scrypt"
+
+	keySize = 32
+)
+
+// scrypt parameters recommended for interactive logins
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// ErrNotFound is returned for providers without a stored key
+var ErrNotFound = errors.New("no stored key")
+
+// Store is an encrypted credentials file
+type Store struct {
+	Dir string
+
+	// Passphrase returns the passphrase of a store that uses one. When
+	// creating a store, an empty passphrase means a key file is used instead.
+	Passphrase func(create bool) (string, error)
+
+	key []byte // Cached encryption key
+}
+
+// Entry describes a stored key without revealing it
+type Entry struct {
+	Provider string    `json:"provider"`
+	Updated  time.Time `json:"updated"`
+}
+
+type storeFile struct {
+	Version int              `json:"version"`
+	KDF     string           `json:"kdf"`
+	Salt    string           `json:"salt,omitempty"` // Hex, for scrypt
+	Entries map[string]entry `json:"entries"`
+}
+
+type entry struct {
+	Nonce   string    `json:"nonce"` // Hex
+	Data    string    `json:"data"`  // Hex ciphertext
+	Updated time.Time `json:"updated"`
+}
+
+// Default returns the store in the global config directory, taking the
+// passphrase from $SLOP_PASSPHRASE
+func Default() (*Store, error) {
+	dir, err := config.GlobalDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Store{Dir: dir, Passphrase: EnvPassphrase}, nil
+}
+
+// EnvPassphrase returns $SLOP_PASSPHRASE. It is an error if an existing
+// store needs a passphrase and it is not set.
+func EnvPassphrase(create bool) (string, error) {
+	passphrase := os.Getenv(PassphraseEnv)
+	if passphrase == "" && !create {
+		return "", fmt.Errorf("stored keys are encrypted with a passphrase, set %s", PassphraseEnv)
+	}
+	return passphrase, nil
+}
+
+// Path returns the path of the credentials file
+func (s *Store) Path() string {
+	return filepath.Join(s.Dir, fileName)
+}
+
+// UsesPassphrase reports whether the store is encrypted with a passphrase
+func (s *Store) UsesPassphrase() (bool, error) {
+	f, err := s.read()
+	if err != nil {
+		return false, err
+	}
+	return f.KDF == kdfScrypt, nil
+}
+
+// Get returns the key stored for provider, or ErrNotFound
+func (s *Store) Get(provider string) (string, error) {
+	f, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	e, ok := f.Entries[provider]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	gcm, err := s.cipher(f, false)
+	if err != nil {
+		return "", err
+	}
+	return decrypt(f, gcm, provider, e)
+}
+
+// Set stores key for provider, creating the store if needed
+func (s *Store) Set(provider, key string) error {
+	f, err := s.read()
+	if err != nil {
+		return err
+	}
+	gcm, err := s.cipher(f, len(f.Entries) == 0)
+	if err != nil {
+		return err
+	}
+	// Check the key against an existing entry so that a wrong passphrase
+	// does not leave entries encrypted with different keys
+	if providers := sortedProviders(f); len(providers) > 0 {
+		if _, err := decrypt(f, gcm, providers[0], f.Entries[providers[0]]); err != nil {
+			return err
+		}
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	f.Entries[provider] = entry{
+		Nonce:   hex.EncodeToString(nonce),
+		Data:    hex.EncodeToString(gcm.Seal(nil, nonce, []byte(key), []byte(provider))),
+		Updated: time.Now(),
+	}
+	return s.write(f)
+}
+
+// Remove deletes the key stored for provider
+func (s *Store) Remove(provider string) error {
+	f, err := s.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := f.Entries[provider]; !ok {
+		return ErrNotFound
+	}
+	delete(f.Entries, provider)
+	return s.write(f)
+}
+
+// List returns the stored keys sorted by provider
+func (s *Store) List() ([]Entry, error) {
+	f, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(f.Entries))
+	for provider, e := range f.Entries {
+		entries = append(entries, Entry{Provider: provider, Updated: e.Updated})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Provider < entries[j].Provider
+	})
+	return entries, nil
+}
+
+// read loads the store, returning an empty one if it does not exist
+func (s *Store) read() (*storeFile, error) {
+	data, err := os.ReadFile(s.Path())
+	if os.IsNotExist(err) {
+		return &storeFile{Version: 1, Entries: make(map[string]entry)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f storeFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s.Path(), err)
+	}
+	if f.Entries == nil {
+		f.Entries = make(map[string]entry)
+	}
+	return &f, nil
+}
+
+func (s *Store) write(f *storeFile) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename it so the store is never left
+	// partially written
+	tmp, err := os.CreateTemp(s.Dir, fileName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path())
+}
+
+// decrypt opens the entry stored for provider
+func decrypt(f *storeFile, gcm cipher.AEAD, provider string, e entry) (string, error) {
+	nonce, err := hex.DecodeString(e.Nonce)
+	if err != nil {
+		return "", fmt.Errorf("corrupt credentials file: %w", err)
+	}
+	data, err := hex.DecodeString(e.Data)
+	if err != nil {
+		return "", fmt.Errorf("corrupt credentials file: %w", err)
+	}
+	plain, err := gcm.Open(nil, nonce, data, []byte(provider))
+	if err != nil {
+		if f.KDF == kdfScrypt {
+			return "", fmt.Errorf("failed to decrypt key for %s, is the passphrase correct?", provider)
+		}
+		return "", fmt.Errorf("failed to decrypt key for %s, was %s replaced?", provider, keyFileName)
+	}
+	return string(plain), nil
+}
+
+func sortedProviders(f *storeFile) []string {
+	providers := make([]string, 0, len(f.Entries))
+	for provider := range f.Entries {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// cipher returns the AES-GCM cipher for f. A store without entries is
+// (re)initialized, choosing between a passphrase and a key file.
+func (s *Store) cipher(f *storeFile, initialize bool) (cipher.AEAD, error) {
+	if initialize {
+		passphrase, err := s.passphrase(true)
+		if err != nil {
+			return nil, err
+		}
+		s.key = nil
+		f.KDF, f.Salt = kdfKeyFile, ""
+		if passphrase != "" {
+			salt := make([]byte, 16)
+			if _, err := rand.Read(salt); err != nil {
+				return nil, err
+			}
+			f.KDF, f.Salt = kdfScrypt, hex.EncodeToString(salt)
+			if s.key, err = deriveKey(passphrase, salt); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if s.key == nil {
+		var err error
+		switch f.KDF {
+		case kdfKeyFile:
+			s.key, err = s.keyFile()
+		case kdfScrypt:
+			var salt []byte
+			var passphrase string
+			if salt, err = hex.DecodeString(f.Salt); err != nil {
+				return nil, fmt.Errorf("corrupt credentials file: %w", err)
+			}
+			if passphrase, err = s.passphrase(false); err != nil {
+				return nil, err
+			}
+			s.key, err = deriveKey(passphrase, salt)
+		default:
+			return nil, fmt.Errorf("unknown encryption %q in %s", f.KDF, s.Path())
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *Store) passphrase(create bool) (string, error) {
+	if s.Passphrase == nil {
+		if create {
+			return "", nil
+		}
+		return "", fmt.Errorf("stored keys are encrypted with a passphrase")
+	}
+	passphrase, err := s.Passphrase(create)
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" && !create {
+		return "", fmt.Errorf("stored keys are encrypted with a passphrase")
+	}
+	return passphrase, nil
+}
+
+// keyFile reads the key file, creating it if it does not exist
+func (s *Store) keyFile() ([]byte, error) {
+	path := filepath.Join(s.Dir, keyFileName)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("invalid key file %s", path)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0o600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keySize)
+}
@@ -0,0 +1,139 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/mcp"
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+const dialTimeout = time.Second
+
+// Client talks to MCP servers owned by a running daemon
+type Client struct {
+	rpc *rpc.Client
+}
+
+var _ mcp.Service = (*Client)(nil)
+
+// Dial connects to the daemon socket
+func Dial() (*Client, error) {
+	conn, err := net.DialTimeout("unix", SocketPath(), dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{rpc: jsonrpc.NewClient(conn)}, nil
+}
+
+// newRequest describes ctx to the daemon so that it can stop the call when
+// ctx is done
+func newRequest(ctx context.Context) Request {
+	req := Request{ID: uuid.NewString()}
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Deadline = deadline
+	}
+	return req
+}
+
+// call invokes a daemon method, giving up when ctx is cancelled
+func (c *Client) call(ctx context.Context, method string, args interface{}, reply interface{}) error {
+	call := c.rpc.Go(rpcName+"."+method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-ctx.Done():
+		if r, ok := args.(interface{ request() Request }); ok {
+			// Stop the call in the daemon too, without waiting for the reply
+			c.rpc.Go(rpcName+".Cancel", r.request(), &Empty{}, nil)
+		}
+		return ctx.Err()
+	case <-call.Done:
+		if call.Error != nil {
+			return fmt.Errorf("daemon: %w", call.Error)
+		}
+		return nil
+	}
+}
+
+// DaemonStatus returns information about the running daemon
+func (c *Client) DaemonStatus(ctx context.Context) (*Status, error) {
+	var status Status
+	if err := c.call(ctx, "Status", Empty{}, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// StopDaemon asks the daemon to stop its servers and exit
+func (c *Client) StopDaemon(ctx context.Context) error {
+	return c.call(ctx, "Shutdown", Empty{}, &Empty{})
+}
+
+// GetTools returns a map of all tools available through the daemon
+func (c *Client) GetTools() map[string]config.Tool {
+	tools := make(map[string]config.Tool)
+	if err := c.call(context.Background(), "GetTools", Empty{}, &tools); err != nil {
+		return make(map[string]config.Tool)
+	}
+	return tools
+}
+
+func (c *Client) CallTool(ctx context.Context, name string, arguments interface{}) (*mcp_golang.ToolResponse, error) {
+	var response mcp_golang.ToolResponse
+	if err := c.call(ctx, "CallTool", CallToolArgs{Request: newRequest(ctx), Name: name, Arguments: arguments}, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (c *Client) ListResources(ctx context.Context) ([]mcp.Resource, error) {
+	var resources []mcp.Resource
+	if err := c.call(ctx, "ListResources", newRequest(ctx), &resources); err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+func (c *Client) ReadResource(ctx context.Context, uri string) (string, error) {
+	var contents string
+	if err := c.call(ctx, "ReadResource", ReadResourceArgs{Request: newRequest(ctx), URI: uri}, &contents); err != nil {
+		return "", err
+	}
+	return contents, nil
+}
+
+func (c *Client) ListPrompts(ctx context.Context) ([]mcp.Prompt, error) {
+	var prompts []mcp.Prompt
+	if err := c.call(ctx, "ListPrompts", newRequest(ctx), &prompts); err != nil {
+		return nil, err
+	}
+	return prompts, nil
+}
+
+func (c *Client) GetPrompt(ctx context.Context, serverName, promptName string, arguments map[string]string) (string, error) {
+	var text string
+	args := GetPromptArgs{Request: newRequest(ctx), Server: serverName, Name: promptName, Arguments: arguments}
+	if err := c.call(ctx, "GetPrompt", args, &text); err != nil {
+		return "", err
+	}
+	return text, nil
+}
+
+// Status returns the state of each of the daemon's servers
+func (c *Client) Status() []mcp.ServerStatus {
+	var statuses []mcp.ServerStatus
+	if err := c.call(context.Background(), "ServerStatus", Empty{}, &statuses); err != nil {
+		return nil
+	}
+	return statuses
+}
+
+// Shutdown closes the connection to the daemon. The daemon's servers keep running.
+func (c *Client) Shutdown() {
+	_ = c.rpc.Close()
+}
@@ -0,0 +1,256 @@
+package daemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/mcp"
+)
+
+/*
+The daemon owns long lived MCP server processes so that each CLI invocation
+does not have to spawn (and later kill) every configured server.
+
+It listens on a unix socket in the user's runtime directory and serves the
+mcp.Service operations over JSON-RPC. The CLI connects to it when it is
+running with the same server configuration, and otherwise falls back to
+starting servers in-process.
+*/
+
+const startTimeout = 60 * time.Second
+
+// Dir returns the directory holding the daemon socket, pid file and log
+func Dir() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "slop")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("slop-%d", os.Getuid()))
+}
+
+// SocketPath returns the path of the daemon's unix socket
+func SocketPath() string {
+	return filepath.Join(Dir(), "mcp.sock")
+}
+
+func pidPath() string {
+	return filepath.Join(Dir(), "daemon.pid")
+}
+
+// LogPath returns the path the daemon's output is written to
+func LogPath() string {
+	return filepath.Join(Dir(), "daemon.log")
+}
+
+// Fingerprint identifies a set of server configurations, so that the CLI
+// only uses a daemon that was started with the servers it expects. The
+// socket is shared by every project, so the working directory that relative
+// commands, args and cwd depend on is included. References are compared
+// unresolved, so computing the fingerprint never runs commands or reads
+// secrets.
+func Fingerprint(servers map[string]config.MCPServer) string {
+	wd, _ := os.Getwd()
+
+	// encoding/json sorts map keys, so this is stable
+	data, _ := json.Marshal(struct {
+		Dir     string
+		Servers map[string]config.MCPServer
+	}{wd, servers})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Run starts all servers and serves them on the daemon socket until ctx is
+// cancelled or a client requests shutdown
+func Run(ctx context.Context, servers map[string]config.MCPServer) error {
+	if err := os.MkdirAll(Dir(), 0700); err != nil {
+		return fmt.Errorf("failed to create daemon directory: %w", err)
+	}
+
+	if client, err := Dial(); err == nil {
+		client.Shutdown()
+		return fmt.Errorf("daemon already running")
+	}
+	// Remove a stale socket left behind by a daemon that did not exit cleanly
+	_ = os.Remove(SocketPath())
+
+	mcpClient := mcp.New(servers)
+	if err := mcpClient.Initialize(ctx); err != nil {
+		return fmt.Errorf("failed to initialize MCP client: %w", err)
+	}
+	defer mcpClient.Shutdown()
+
+	listener, err := net.Listen("unix", SocketPath())
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", SocketPath(), err)
+	}
+	defer os.Remove(SocketPath())
+
+	if err := os.WriteFile(pidPath(), []byte(strconv.Itoa(os.Getpid())), 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to write pid file: %w", err)
+	}
+	defer os.Remove(pidPath())
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	service := &rpcService{
+		ctx:         ctx,
+		mcp:         mcpClient,
+		fingerprint: Fingerprint(servers),
+		startedAt:   time.Now(),
+		shutdown:    cancel,
+	}
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	slog.Info("mcp daemon started", "socket", SocketPath(), "servers", len(servers))
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				slog.Info("mcp daemon stopped")
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+		go serveConn(ctx, service, conn)
+	}
+}
+
+// serveConn serves a single connection. Calls still running when the client
+// disconnects are cancelled.
+func serveConn(ctx context.Context, service *rpcService, conn net.Conn) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	server := rpc.NewServer()
+	if err := server.RegisterName(rpcName, service.forConn(ctx)); err != nil {
+		slog.Error("failed to register daemon service", "error", err)
+		conn.Close()
+		return
+	}
+	server.ServeCodec(jsonrpc.NewServerCodec(closeNotifier{Conn: conn, cancel: cancel}))
+}
+
+// closeNotifier cancels a connection's calls once reading from it fails.
+// ServeCodec waits for running calls before returning, so it cannot be used
+// to notice the client has gone.
+type closeNotifier struct {
+	net.Conn
+	cancel context.CancelFunc
+}
+
+func (c closeNotifier) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if err != nil {
+		c.cancel()
+	}
+	return n, err
+}
+
+// Start launches the daemon in the background and waits for it to accept connections
+func Start(args []string) (int, error) {
+	if client, err := Dial(); err == nil {
+		client.Shutdown()
+		return 0, fmt.Errorf("daemon already running")
+	}
+
+	if err := os.MkdirAll(Dir(), 0700); err != nil {
+		return 0, fmt.Errorf("failed to create daemon directory: %w", err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("failed to find slop executable: %w", err)
+	}
+
+	logFile, err := os.OpenFile(LogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open daemon log: %w", err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(executable, append([]string{"daemon", "run"}, args...)...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = detachedProcAttr()
+
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start daemon: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+
+	deadline := time.After(startTimeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-exited:
+			return 0, fmt.Errorf("daemon exited during startup (%v), see %s", err, LogPath())
+		case <-deadline:
+			return 0, fmt.Errorf("timed out waiting for daemon to start, see %s", LogPath())
+		case <-ticker.C:
+			if client, err := Dial(); err == nil {
+				client.Shutdown()
+				return cmd.Process.Pid, nil
+			}
+		}
+	}
+}
+
+// Stop asks a running daemon to shut down
+func Stop(ctx context.Context) error {
+	client, err := Dial()
+	if err != nil {
+		return ErrNotRunning
+	}
+	defer client.Shutdown()
+
+	return client.StopDaemon(ctx)
+}
+
+// ErrNotRunning is returned when no daemon is listening on the socket
+var ErrNotRunning = errors.New("daemon is not running")
+
+// Connect returns an MCP service backed by the daemon if one is running with
+// the same server configuration, and otherwise starts the servers in-process
+func Connect(ctx context.Context, servers map[string]config.MCPServer) (mcp.Service, error) {
+	if client, err := Dial(); err == nil {
+		status, err := client.DaemonStatus(ctx)
+		if err == nil && status.Fingerprint == Fingerprint(servers) {
+			slog.Debug("using mcp daemon", "socket", SocketPath(), "pid", status.PID)
+			return client, nil
+		}
+		if err == nil {
+			slog.Debug("mcp daemon configuration or directory differs, starting servers in-process")
+		}
+		client.Shutdown()
+	}
+
+	client := mcp.New(servers)
+	if err := client.Initialize(ctx); err != nil {
+		return nil, fmt.Errorf("failed to initialize MCP client: %w", err)
+	}
+	return client, nil
+}
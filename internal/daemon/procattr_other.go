@@ -0,0 +1,9 @@
+//go:build !unix
+
+package daemon
+
+import "syscall"
+
+func detachedProcAttr() *syscall.SysProcAttr {
+	return nil
+}
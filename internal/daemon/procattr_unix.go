@@ -0,0 +1,10 @@
+//go:build unix
+
+package daemon
+
+import "syscall"
+
+// detachedProcAttr starts the daemon in its own session so it outlives the CLI
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,185 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/mcp"
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+const rpcName = "Daemon"
+
+// Status describes a running daemon
+type Status struct {
+	PID         int
+	StartedAt   time.Time
+	Fingerprint string
+	ToolCount   int
+}
+
+type Empty struct{}
+
+// Request carries the context of a call that talks to MCP servers. The call
+// is cancelled at the deadline, when Cancel is called with its ID or when
+// the client disconnects.
+type Request struct {
+	ID       string
+	Deadline time.Time
+}
+
+func (r Request) request() Request {
+	return r
+}
+
+type CallToolArgs struct {
+	Request
+	Name      string
+	Arguments interface{}
+}
+
+type ReadResourceArgs struct {
+	Request
+	URI string
+}
+
+type GetPromptArgs struct {
+	Request
+	Server    string
+	Name      string
+	Arguments map[string]string
+}
+
+// rpcService exposes an mcp.Client over net/rpc. Every exported method is an
+// RPC endpoint. Each connection has its own service, see forConn.
+type rpcService struct {
+	ctx         context.Context // Cancelled when the connection closes
+	mcp         *mcp.Client
+	fingerprint string
+	startedAt   time.Time
+	shutdown    func()
+	calls       sync.Map // Request ID to context.CancelFunc
+}
+
+// forConn returns a service for a single connection
+func (s *rpcService) forConn(ctx context.Context) *rpcService {
+	return &rpcService{
+		ctx:         ctx,
+		mcp:         s.mcp,
+		fingerprint: s.fingerprint,
+		startedAt:   s.startedAt,
+		shutdown:    s.shutdown,
+	}
+}
+
+// begin returns the context for a request and a function to call when it is done
+func (s *rpcService) begin(req Request) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	if !req.Deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(s.ctx, req.Deadline)
+	}
+	if req.ID == "" {
+		return ctx, cancel
+	}
+	s.calls.Store(req.ID, cancel)
+	return ctx, func() {
+		s.calls.Delete(req.ID)
+		cancel()
+	}
+}
+
+// Cancel abandons a running call, for clients that give up waiting for it
+func (s *rpcService) Cancel(req Request, _ *Empty) error {
+	if cancel, ok := s.calls.LoadAndDelete(req.ID); ok {
+		cancel.(context.CancelFunc)()
+	}
+	return nil
+}
+
+func (s *rpcService) Status(_ Empty, reply *Status) error {
+	*reply = Status{
+		PID:         os.Getpid(),
+		StartedAt:   s.startedAt,
+		Fingerprint: s.fingerprint,
+		ToolCount:   len(s.mcp.GetTools()),
+	}
+	return nil
+}
+
+func (s *rpcService) ServerStatus(_ Empty, reply *[]mcp.ServerStatus) error {
+	*reply = append([]mcp.ServerStatus{}, s.mcp.Status()...)
+	return nil
+}
+
+func (s *rpcService) Shutdown(_ Empty, _ *Empty) error {
+	// Reply before the listener is torn down
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		s.shutdown()
+	}()
+	return nil
+}
+
+func (s *rpcService) GetTools(_ Empty, reply *map[string]config.Tool) error {
+	*reply = s.mcp.GetTools()
+	return nil
+}
+
+func (s *rpcService) CallTool(args CallToolArgs, reply *mcp_golang.ToolResponse) error {
+	ctx, done := s.begin(args.Request)
+	defer done()
+	result, err := s.mcp.CallTool(ctx, args.Name, args.Arguments)
+	if err != nil {
+		return err
+	}
+	*reply = *result
+	return nil
+}
+
+func (s *rpcService) ListResources(args Request, reply *[]mcp.Resource) error {
+	ctx, done := s.begin(args)
+	defer done()
+	resources, err := s.mcp.ListResources(ctx)
+	if err != nil {
+		return err
+	}
+	// jsonrpc treats a null result as an error, so always reply with a list
+	*reply = append([]mcp.Resource{}, resources...)
+	return nil
+}
+
+func (s *rpcService) ReadResource(args ReadResourceArgs, reply *string) error {
+	ctx, done := s.begin(args.Request)
+	defer done()
+	contents, err := s.mcp.ReadResource(ctx, args.URI)
+	if err != nil {
+		return err
+	}
+	*reply = contents
+	return nil
+}
+
+func (s *rpcService) ListPrompts(args Request, reply *[]mcp.Prompt) error {
+	ctx, done := s.begin(args)
+	defer done()
+	prompts, err := s.mcp.ListPrompts(ctx)
+	if err != nil {
+		return err
+	}
+	*reply = append([]mcp.Prompt{}, prompts...)
+	return nil
+}
+
+func (s *rpcService) GetPrompt(args GetPromptArgs, reply *string) error {
+	ctx, done := s.begin(args.Request)
+	defer done()
+	text, err := s.mcp.GetPrompt(ctx, args.Server, args.Name, args.Arguments)
+	if err != nil {
+		return err
+	}
+	*reply = text
+	return nil
+}
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -33,9 +35,51 @@ type Message struct {
 	ToolCalls string `gorm:"type:text"`
 	ModelName string `gorm:"type:text"`
 	Provider  string `gorm:"type:text"`
+
+	InputTokens  int
+	OutputTokens int
+	gorm.Model
+}
+
+type ApprovalDecision string
+
+const (
+	ApprovalPending  ApprovalDecision = "pending"
+	ApprovalApproved ApprovalDecision = "approved"
+	ApprovalDenied   ApprovalDecision = "denied"
+)
+
+// ApprovedByConfig is recorded as the approver when agent.autoApproveFunctions is set
+const ApprovedByConfig = "config:autoApproveFunctions"
+
+// ToolExecution is the audit record of a single tool call requested by the model.
+// Records are kept when their thread is deleted.
+type ToolExecution struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key"`
+	CallID     string    `gorm:"type:text;index"`
+	ThreadID   uuid.UUID `gorm:"type:uuid;index"`
+	MessageID  uuid.UUID `gorm:"type:uuid;index"` // Assistant message that requested the call
+	Server     string    `gorm:"type:text;index"` // MCP server, or "native" for tools run by slop
+	Tool       string    `gorm:"type:text;index"` // Full tool name as offered to the model
+	Arguments  string    `gorm:"type:text"`
+	Result     string    `gorm:"type:text"`
+	Error      string    `gorm:"type:text"`
+	Duration   time.Duration
+	Approval   ApprovalDecision `gorm:"type:text"`
+	ApprovedBy string           `gorm:"type:text"`
 	gorm.Model
 }
 
+// ToolExecutionFilter selects tool executions, zero values match everything
+type ToolExecutionFilter struct {
+	ThreadID  *uuid.UUID
+	MessageID *uuid.UUID
+	Tool      string // Glob, e.g. filesystem__*
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+}
+
 func (t *Thread) BeforeCreate(tx *gorm.DB) (err error) {
 	if t.ID == uuid.Nil {
 		t.ID = uuid.New()
@@ -49,3 +93,10 @@ func (m *Message) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	return
 }
+
+func (e *ToolExecution) BeforeCreate(tx *gorm.DB) (err error) {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return
+}
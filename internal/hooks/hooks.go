@@ -0,0 +1,197 @@
+// Package hooks runs user commands on lifecycle events.
+//
+// Hooks are shell commands from the hooks section of config that run on events
+// emitted by the message service and the agent:
+//
+//	beforeSend       before a message is sent to the model
+//	afterSend        after the model's response is saved
+//	beforeToolCall   before a tool runs, after its arguments are validated
+//	afterToolCall    after a tool returns
+//	threadCreate     after a thread is created
+//	threadDelete     after a thread is deleted
+//
+// Each hook receives a Payload as JSON on stdin, with the event name also in
+// SLOP_EVENT. Hooks for before events may print a Response as JSON on stdout to
+// deny the event or rewrite the message content or tool arguments. A before hook
+// that exits with a non-zero status or prints invalid JSON denies the event, so
+// policy hooks fail closed. Output from other hooks is ignored and their
+// failures are only logged.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/slop/internal/config"
+)
+
+type Event string
+
+const (
+	BeforeSend     Event = "beforeSend"
+	AfterSend      Event = "afterSend"
+	BeforeToolCall Event = "beforeToolCall"
+	AfterToolCall  Event = "afterToolCall"
+	ThreadCreate   Event = "threadCreate"
+	ThreadDelete   Event = "threadDelete"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// Payload describes an event. Only the fields relevant to the event are set.
+type Payload struct {
+	Event     Event           `json:"event"`
+	ThreadID  string          `json:"threadId,omitempty"`
+	MessageID string          `json:"messageId,omitempty"`
+	Model     string          `json:"model,omitempty"`
+	Content   string          `json:"content,omitempty"`   // Message sent to the model
+	Response  string          `json:"response,omitempty"`  // Text of the model's reply
+	ToolCalls json.RawMessage `json:"toolCalls,omitempty"` // Tool calls in the model's reply
+	Tool      string          `json:"tool,omitempty"`
+	CallID    string          `json:"callId,omitempty"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Result    string          `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// Response is what a hook may print to change a before event
+type Response struct {
+	Decision  string          `json:"decision,omitempty"` // "deny" to stop the event
+	Reason    string          `json:"reason,omitempty"`
+	Content   *string         `json:"content,omitempty"`   // beforeSend: replacement message
+	Arguments json.RawMessage `json:"arguments,omitempty"` // beforeToolCall: replacement arguments
+}
+
+// DeniedError is returned when a hook denies a before event
+type DeniedError struct {
+	Event  Event
+	Hook   string
+	Reason string
+}
+
+func (e *DeniedError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("%s denied by hook %q", e.Event, e.Hook)
+	}
+	return fmt.Sprintf("%s denied by hook %q: %s", e.Event, e.Hook, e.Reason)
+}
+
+// Runner runs the configured hooks. A nil Runner runs nothing.
+type Runner struct {
+	hooks map[Event][]config.Hook
+}
+
+// New creates a runner for the hooks in config
+func New(cfg config.Hooks) *Runner {
+	return &Runner{
+		hooks: map[Event][]config.Hook{
+			BeforeSend:     cfg.BeforeSend,
+			AfterSend:      cfg.AfterSend,
+			BeforeToolCall: cfg.BeforeToolCall,
+			AfterToolCall:  cfg.AfterToolCall,
+			ThreadCreate:   cfg.ThreadCreate,
+			ThreadDelete:   cfg.ThreadDelete,
+		},
+	}
+}
+
+// Before runs the hooks for a before event in order, each seeing the changes
+// made by the previous ones. It returns the payload as modified by the hooks,
+// or a *DeniedError if one of them denied the event.
+func (r *Runner) Before(ctx context.Context, p Payload) (Payload, error) {
+	for _, hook := range r.matching(p) {
+		output, err := run(ctx, hook, p)
+		if err != nil {
+			return p, &DeniedError{Event: p.Event, Hook: hook.Command, Reason: err.Error()}
+		}
+		if len(output) == 0 {
+			continue
+		}
+
+		var resp Response
+		if err := json.Unmarshal(output, &resp); err != nil {
+			return p, &DeniedError{Event: p.Event, Hook: hook.Command, Reason: fmt.Sprintf("invalid hook output: %v", err)}
+		}
+		if strings.EqualFold(resp.Decision, "deny") {
+			return p, &DeniedError{Event: p.Event, Hook: hook.Command, Reason: resp.Reason}
+		}
+		if resp.Content != nil {
+			p.Content = *resp.Content
+		}
+		if len(resp.Arguments) > 0 {
+			p.Arguments = resp.Arguments
+		}
+	}
+	return p, nil
+}
+
+// After runs the hooks for a notification event, logging any failures
+func (r *Runner) After(ctx context.Context, p Payload) {
+	for _, hook := range r.matching(p) {
+		if _, err := run(ctx, hook, p); err != nil {
+			slog.Warn("hook failed", "event", p.Event, "hook", hook.Command, "error", err)
+		}
+	}
+}
+
+// matching returns the hooks for the payload's event, filtered by tool name
+func (r *Runner) matching(p Payload) []config.Hook {
+	if r == nil {
+		return nil
+	}
+	var hooks []config.Hook
+	for _, hook := range r.hooks[p.Event] {
+		if hook.Match != "" && p.Tool != "" {
+			if ok, _ := path.Match(hook.Match, p.Tool); !ok {
+				continue
+			}
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks
+}
+
+// run executes a hook with the payload on stdin and returns its trimmed stdout
+func run(ctx context.Context, hook config.Hook, p Payload) ([]byte, error) {
+	input, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", hook.Command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), "SLOP_EVENT="+string(p.Event))
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %s", timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	if stderr.Len() > 0 {
+		slog.Debug("hook stderr", "event", p.Event, "hook", hook.Command, "output", stderr.String())
+	}
+
+	return bytes.TrimSpace(stdout.Bytes()), nil
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/isaacphi/slop/internal/config"
@@ -17,9 +18,9 @@ type InternalService struct {
 }
 
 func NewInternalService(cfg *config.ConfigSchema) (*InternalService, error) {
-	modelCfg, ok := cfg.Models[cfg.Internal.Model]
-	if !ok {
-		return nil, fmt.Errorf("model %s not found in configuration", cfg.ActiveModel)
+	_, modelCfg, err := cfg.ResolveModel(cfg.SummaryModel())
+	if err != nil {
+		return nil, err
 	}
 
 	llmClient, err := llm.NewClient(modelCfg)
@@ -42,6 +43,15 @@ func (s *InternalService) GenerateOneOff(ctx context.Context, prompt string) (st
 	return response.TextResponse, nil
 }
 
+// GenerateStructured makes a single call to the LLM for a JSON value matching schema
+func (s *InternalService) GenerateStructured(ctx context.Context, prompt string, schema map[string]interface{}) (json.RawMessage, error) {
+	response, err := s.llm.GenerateStructured(ctx, prompt, []domain.Message{}, schema)
+	if err != nil {
+		return nil, fmt.Errorf("internal message failed: %w", err)
+	}
+	return json.RawMessage(response.TextResponse), nil
+}
+
 // CreateThreadSummary generates a summary for a thread using the internal model
 func (s *InternalService) CreateThreadSummary(ctx context.Context, messages []domain.Message) (string, error) {
 	if len(messages) == 0 {
@@ -0,0 +1,648 @@
+// Package jsonschema validates JSON values against a JSON Schema.
+//
+// It implements the validation vocabulary of draft 2020-12 that tool schemas use
+// in practice:
+//
+//	type, enum, const
+//	properties, required, additionalProperties, patternProperties,
+//	propertyNames, minProperties, maxProperties, dependentRequired
+//	items, prefixItems, contains, minItems, maxItems, uniqueItems
+//	minLength, maxLength, pattern
+//	minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf
+//	allOf, anyOf, oneOf, not, if/then/else
+//	$ref to local definitions ("#", "#/$defs/...", "#/definitions/...")
+//
+// format is treated as an annotation, as the specification allows, and remote
+// $refs are not supported. Patterns use Go's RE2 syntax.
+//
+// Values must be in the form produced by encoding/json: map[string]interface{},
+// []interface{}, string, float64, bool and nil.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error is a single validation failure. Path is a JSON pointer to the
+// offending value, empty for the root.
+type Error struct {
+	Path    string
+	Message string
+}
+
+func (e Error) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationError collects every failure found in a value
+type ValidationError struct {
+	Errors []Error
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate checks value against schema. It returns a *ValidationError
+// describing every failure, or an error if the schema itself is invalid.
+func Validate(schema interface{}, value interface{}) error {
+	schema, err := normalize(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	v := &validator{root: schema, patterns: make(map[string]*regexp.Regexp)}
+	errs := v.validate(schema, value, "", 0)
+	if v.schemaErr != nil {
+		return fmt.Errorf("invalid schema: %w", v.schemaErr)
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// ApplyDefaults fills in missing object properties that declare a default,
+// recursing into nested objects and arrays. It returns the updated value.
+func ApplyDefaults(schema interface{}, value interface{}) interface{} {
+	schema, err := normalize(schema)
+	if err != nil {
+		return value
+	}
+	return applyDefaults(schema, schema, value, 0)
+}
+
+// maxDepth guards against $ref cycles
+const maxDepth = 64
+
+type validator struct {
+	root      interface{}
+	patterns  map[string]*regexp.Regexp
+	schemaErr error
+}
+
+func (v *validator) validate(schema interface{}, value interface{}, path string, depth int) []Error {
+	if depth > maxDepth {
+		v.schemaErr = fmt.Errorf("schema nesting too deep at %s, possible $ref cycle", pointerOrRoot(path))
+		return nil
+	}
+
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			return []Error{{path, "no value is allowed here"}}
+		}
+		return nil
+	case map[string]interface{}:
+		return v.validateObjectSchema(s, value, path, depth)
+	default:
+		v.schemaErr = fmt.Errorf("schema at %s must be an object or boolean", pointerOrRoot(path))
+		return nil
+	}
+}
+
+func (v *validator) validateObjectSchema(s map[string]interface{}, value interface{}, path string, depth int) []Error {
+	var errs []Error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, Error{path, fmt.Sprintf(format, args...)})
+	}
+
+	if ref, ok := s["$ref"].(string); ok {
+		target, err := v.resolveRef(ref)
+		if err != nil {
+			v.schemaErr = err
+			return nil
+		}
+		errs = append(errs, v.validate(target, value, path, depth+1)...)
+	}
+
+	if t, ok := s["type"]; ok {
+		types := stringList(t)
+		matched := false
+		for _, typ := range types {
+			if hasType(value, typ) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("expected %s, got %s", strings.Join(types, " or "), typeOf(value))
+			// Further keywords would only repeat the type mismatch
+			return errs
+		}
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if equal(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("must be one of %s", formatValue(enum))
+		}
+	}
+
+	if c, ok := s["const"]; ok && !equal(c, value) {
+		fail("must be %s", formatValue(c))
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		errs = append(errs, v.validateObject(s, val, path, depth)...)
+	case []interface{}:
+		errs = append(errs, v.validateArray(s, val, path, depth)...)
+	case string:
+		errs = append(errs, v.validateString(s, val, path)...)
+	case float64:
+		errs = append(errs, validateNumber(s, val, path)...)
+	}
+
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			errs = append(errs, v.validate(sub, value, path, depth+1)...)
+		}
+	}
+
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		var branchErrs []Error
+		matched := false
+		for _, sub := range anyOf {
+			subErrs := v.validate(sub, value, path, depth+1)
+			if len(subErrs) == 0 {
+				matched = true
+				break
+			}
+			branchErrs = append(branchErrs, subErrs...)
+		}
+		if !matched {
+			fail("must match at least one schema in anyOf (%s)", summarize(branchErrs))
+		}
+	}
+
+	if one, ok := s["oneOf"].([]interface{}); ok {
+		var branchErrs []Error
+		matches := 0
+		for _, sub := range one {
+			subErrs := v.validate(sub, value, path, depth+1)
+			if len(subErrs) == 0 {
+				matches++
+			} else {
+				branchErrs = append(branchErrs, subErrs...)
+			}
+		}
+		switch {
+		case matches == 0:
+			fail("must match exactly one schema in oneOf (%s)", summarize(branchErrs))
+		case matches > 1:
+			fail("must match exactly one schema in oneOf, matched %d", matches)
+		}
+	}
+
+	if not, ok := s["not"]; ok {
+		if len(v.validate(not, value, path, depth+1)) == 0 {
+			fail("must not match the schema in not")
+		}
+	}
+
+	if cond, ok := s["if"]; ok {
+		if len(v.validate(cond, value, path, depth+1)) == 0 {
+			if then, ok := s["then"]; ok {
+				errs = append(errs, v.validate(then, value, path, depth+1)...)
+			}
+		} else if els, ok := s["else"]; ok {
+			errs = append(errs, v.validate(els, value, path, depth+1)...)
+		}
+	}
+
+	return errs
+}
+
+func (v *validator) validateObject(s map[string]interface{}, obj map[string]interface{}, path string, depth int) []Error {
+	var errs []Error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, Error{path, fmt.Sprintf(format, args...)})
+	}
+
+	if required, ok := s["required"].([]interface{}); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, exists := obj[name]; !exists {
+				fail("missing required property %q", name)
+			}
+		}
+	}
+
+	if dependent, ok := s["dependentRequired"].(map[string]interface{}); ok {
+		for name, deps := range dependent {
+			if _, exists := obj[name]; !exists {
+				continue
+			}
+			for _, dep := range stringList(deps) {
+				if _, exists := obj[dep]; !exists {
+					fail("property %q is required when %q is present", dep, name)
+				}
+			}
+		}
+	}
+
+	if n, ok := number(s["minProperties"]); ok && float64(len(obj)) < n {
+		fail("must have at least %v properties", n)
+	}
+	if n, ok := number(s["maxProperties"]); ok && float64(len(obj)) > n {
+		fail("must have at most %v properties", n)
+	}
+
+	properties, _ := s["properties"].(map[string]interface{})
+	patternProperties, _ := s["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := s["additionalProperties"]
+	propertyNames, hasPropertyNames := s["propertyNames"]
+
+	for _, name := range sortedKeys(obj) {
+		value := obj[name]
+		childPath := path + "/" + escapePointer(name)
+
+		if hasPropertyNames {
+			for _, e := range v.validate(propertyNames, name, childPath, depth+1) {
+				errs = append(errs, Error{childPath, "invalid property name: " + e.Message})
+			}
+		}
+
+		matched := false
+		if sub, ok := properties[name]; ok {
+			matched = true
+			errs = append(errs, v.validate(sub, value, childPath, depth+1)...)
+		}
+		for pattern, sub := range patternProperties {
+			re := v.compile(pattern)
+			if re != nil && re.MatchString(name) {
+				matched = true
+				errs = append(errs, v.validate(sub, value, childPath, depth+1)...)
+			}
+		}
+
+		if matched || !hasAdditional {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok && !allowed {
+			errs = append(errs, Error{childPath, "unknown property"})
+			continue
+		}
+		errs = append(errs, v.validate(additional, value, childPath, depth+1)...)
+	}
+
+	return errs
+}
+
+func (v *validator) validateArray(s map[string]interface{}, arr []interface{}, path string, depth int) []Error {
+	var errs []Error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, Error{path, fmt.Sprintf(format, args...)})
+	}
+
+	if n, ok := number(s["minItems"]); ok && float64(len(arr)) < n {
+		fail("must have at least %v items", n)
+	}
+	if n, ok := number(s["maxItems"]); ok && float64(len(arr)) > n {
+		fail("must have at most %v items", n)
+	}
+
+	if unique, _ := s["uniqueItems"].(bool); unique {
+	outer:
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if equal(arr[i], arr[j]) {
+					fail("items %d and %d are equal, items must be unique", i, j)
+					break outer
+				}
+			}
+		}
+	}
+
+	prefix, _ := s["prefixItems"].([]interface{})
+	for i, sub := range prefix {
+		if i >= len(arr) {
+			break
+		}
+		errs = append(errs, v.validate(sub, arr[i], fmt.Sprintf("%s/%d", path, i), depth+1)...)
+	}
+
+	if items, ok := s["items"]; ok {
+		for i := len(prefix); i < len(arr); i++ {
+			errs = append(errs, v.validate(items, arr[i], fmt.Sprintf("%s/%d", path, i), depth+1)...)
+		}
+	}
+
+	if contains, ok := s["contains"]; ok {
+		matches := 0
+		for i, item := range arr {
+			if len(v.validate(contains, item, fmt.Sprintf("%s/%d", path, i), depth+1)) == 0 {
+				matches++
+			}
+		}
+		minContains := 1.0
+		if n, ok := number(s["minContains"]); ok {
+			minContains = n
+		}
+		if float64(matches) < minContains {
+			fail("must contain at least %v matching items", minContains)
+		}
+		if n, ok := number(s["maxContains"]); ok && float64(matches) > n {
+			fail("must contain at most %v matching items", n)
+		}
+	}
+
+	return errs
+}
+
+func (v *validator) validateString(s map[string]interface{}, str string, path string) []Error {
+	var errs []Error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, Error{path, fmt.Sprintf(format, args...)})
+	}
+
+	length := float64(utf8.RuneCountInString(str))
+	if n, ok := number(s["minLength"]); ok && length < n {
+		fail("must be at least %v characters long", n)
+	}
+	if n, ok := number(s["maxLength"]); ok && length > n {
+		fail("must be at most %v characters long", n)
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		if re := v.compile(pattern); re != nil && !re.MatchString(str) {
+			fail("must match pattern %q", pattern)
+		}
+	}
+
+	return errs
+}
+
+func validateNumber(s map[string]interface{}, n float64, path string) []Error {
+	var errs []Error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, Error{path, fmt.Sprintf(format, args...)})
+	}
+
+	if min, ok := number(s["minimum"]); ok && n < min {
+		fail("must be >= %v", min)
+	}
+	if max, ok := number(s["maximum"]); ok && n > max {
+		fail("must be <= %v", max)
+	}
+	if min, ok := number(s["exclusiveMinimum"]); ok && n <= min {
+		fail("must be > %v", min)
+	}
+	if max, ok := number(s["exclusiveMaximum"]); ok && n >= max {
+		fail("must be < %v", max)
+	}
+	if m, ok := number(s["multipleOf"]); ok && m > 0 {
+		if q := n / m; math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("must be a multiple of %v", m)
+		}
+	}
+
+	return errs
+}
+
+func (v *validator) compile(pattern string) *regexp.Regexp {
+	if re, ok := v.patterns[pattern]; ok {
+		return re
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		v.schemaErr = fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	v.patterns[pattern] = re
+	return re
+}
+
+// resolveRef follows a local JSON pointer reference from the root schema
+func (v *validator) resolveRef(ref string) (interface{}, error) {
+	return ResolveRef(v.root, ref)
+}
+
+// ResolveRef follows a local $ref such as "#/$defs/item" from the root schema
+func ResolveRef(root interface{}, ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref %q, only local references are supported", ref)
+	}
+
+	current := root
+	pointer := strings.TrimPrefix(ref, "#")
+	if pointer == "" {
+		return current, nil
+	}
+
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("$ref %q not found", ref)
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("$ref %q not found", ref)
+			}
+			current = node[i]
+		default:
+			return nil, fmt.Errorf("$ref %q not found", ref)
+		}
+	}
+	return current, nil
+}
+
+func applyDefaults(root, schema interface{}, value interface{}, depth int) interface{} {
+	s, ok := schema.(map[string]interface{})
+	if !ok || depth > maxDepth {
+		return value
+	}
+
+	if ref, ok := s["$ref"].(string); ok {
+		if target, err := ResolveRef(root, ref); err == nil {
+			value = applyDefaults(root, target, value, depth+1)
+		}
+	}
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			value = applyDefaults(root, sub, value, depth+1)
+		}
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		properties, _ := s["properties"].(map[string]interface{})
+		for name, sub := range properties {
+			if _, exists := val[name]; !exists {
+				if subSchema, ok := sub.(map[string]interface{}); ok {
+					if def, ok := subSchema["default"]; ok {
+						val[name] = def
+					}
+				}
+			}
+			if current, exists := val[name]; exists {
+				val[name] = applyDefaults(root, sub, current, depth+1)
+			}
+		}
+	case []interface{}:
+		if items, ok := s["items"]; ok {
+			for i := range val {
+				val[i] = applyDefaults(root, items, val[i], depth+1)
+			}
+		}
+	}
+
+	return value
+}
+
+// normalize converts a schema to the form produced by encoding/json, so that
+// schemas built in Go or decoded from YAML compare correctly against values
+func normalize(schema interface{}) (interface{}, error) {
+	if schema == nil {
+		return true, nil
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+func hasType(value interface{}, typ string) bool {
+	switch typ {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return false
+}
+
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// equal compares JSON values. Schemas are normalized so numbers are float64 on both sides.
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func number(v interface{}) (float64, bool) {
+	n, ok := v.(float64)
+	return n, ok
+}
+
+func stringList(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		list := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func pointerOrRoot(path string) string {
+	if path == "" {
+		return "root"
+	}
+	return path
+}
+
+func formatValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// summarize joins branch failures for anyOf and oneOf messages
+func summarize(errs []Error) string {
+	messages := make([]string, 0, len(errs))
+	seen := make(map[string]bool)
+	for _, e := range errs {
+		msg := e.Error()
+		if !seen[msg] {
+			seen[msg] = true
+			messages = append(messages, msg)
+		}
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,216 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("invalid JSON %s: %v", s, err)
+	}
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		want   string // Expected error, empty if the value is valid
+	}{
+		// type
+		{"empty schema", `{}`, `[1, "a"]`, ""},
+		{"true schema", `true`, `null`, ""},
+		{"false schema", `false`, `1`, "no value is allowed here"},
+		{"string", `{"type": "string"}`, `"a"`, ""},
+		{"wrong type", `{"type": "string"}`, `1`, "expected string, got integer"},
+		{"type list", `{"type": ["string", "null"]}`, `null`, ""},
+		{"type list mismatch", `{"type": ["string", "null"]}`, `true`, "expected string or null, got boolean"},
+		{"object", `{"type": "object"}`, `[]`, "expected object, got array"},
+		{"array", `{"type": "array"}`, `{}`, "expected array, got object"},
+
+		// integer
+		{"integer", `{"type": "integer"}`, `3`, ""},
+		{"integer with zero fraction", `{"type": "integer"}`, `3.0`, ""},
+		{"integer with fraction", `{"type": "integer"}`, `3.5`, "expected integer, got number"},
+		{"integer is a number", `{"type": "number"}`, `3`, ""},
+		{"minimum", `{"minimum": 1}`, `0`, "must be >= 1"},
+		{"maximum", `{"maximum": 1}`, `2`, "must be <= 1"},
+		{"exclusiveMinimum", `{"exclusiveMinimum": 1}`, `1`, "must be > 1"},
+		{"exclusiveMaximum", `{"exclusiveMaximum": 1}`, `1`, "must be < 1"},
+		{"multipleOf", `{"multipleOf": 0.1}`, `0.3`, ""},
+		{"not multipleOf", `{"multipleOf": 2}`, `3`, "must be a multiple of 2"},
+
+		// strings
+		{"minLength", `{"minLength": 2}`, `"é"`, "must be at least 2 characters long"},
+		{"maxLength counts characters", `{"maxLength": 1}`, `"é"`, ""},
+		{"pattern", `{"pattern": "^[a-z]+$"}`, `"abc1"`, `must match pattern "^[a-z]+$"`},
+
+		// enum and const
+		{"enum", `{"enum": ["a", 1]}`, `1`, ""},
+		{"enum mismatch", `{"enum": ["a", 1]}`, `"b"`, `must be one of ["a",1]`},
+		{"enum object", `{"enum": [{"a": [1]}]}`, `{"a": [1]}`, ""},
+		{"const", `{"const": "a"}`, `"b"`, `must be "a"`},
+
+		// objects
+		{"required", `{"required": ["a", "b"]}`, `{"a": 1}`, `missing required property "b"`},
+		{
+			"nested properties",
+			`{"properties": {"a": {"properties": {"b": {"type": "string"}}}}}`,
+			`{"a": {"b": 1}}`,
+			"/a/b: expected string, got integer",
+		},
+		{"additionalProperties false", `{"properties": {"a": {}}, "additionalProperties": false}`, `{"a": 1, "b": 2}`, "/b: unknown property"},
+		{"additionalProperties schema", `{"additionalProperties": {"type": "integer"}}`, `{"a": 1, "b": "x"}`, "/b: expected integer, got string"},
+		{"patternProperties", `{"patternProperties": {"^x-": {"type": "string"}}, "additionalProperties": false}`, `{"x-a": "1"}`, ""},
+		{"propertyNames", `{"propertyNames": {"maxLength": 1}}`, `{"ab": 1}`, "/ab: invalid property name: must be at most 1 characters long"},
+		{"minProperties", `{"minProperties": 1}`, `{}`, "must have at least 1 properties"},
+		{"dependentRequired", `{"dependentRequired": {"a": ["b"]}}`, `{"a": 1}`, `property "b" is required when "a" is present`},
+		{"escaped path", `{"properties": {"a/b": {"type": "string"}}}`, `{"a/b": 1}`, "/a~1b: expected string, got integer"},
+
+		// arrays
+		{"items", `{"items": {"type": "string"}}`, `["a", 1]`, "/1: expected string, got integer"},
+		{"prefixItems", `{"prefixItems": [{"type": "string"}], "items": {"type": "integer"}}`, `["a", 1, "b"]`, "/2: expected integer, got string"},
+		{"minItems", `{"minItems": 1}`, `[]`, "must have at least 1 items"},
+		{"maxItems", `{"maxItems": 1}`, `[1, 2]`, "must have at most 1 items"},
+		{"uniqueItems", `{"uniqueItems": true}`, `[1, 2, 1]`, "items 0 and 2 are equal, items must be unique"},
+		{"contains", `{"contains": {"type": "string"}}`, `[1, 2]`, "must contain at least 1 matching items"},
+		{"maxContains", `{"contains": {"type": "string"}, "maxContains": 1}`, `["a", "b"]`, "must contain at most 1 matching items"},
+
+		// composition
+		{"allOf", `{"allOf": [{"minimum": 1}, {"maximum": 2}]}`, `3`, "must be <= 2"},
+		{"anyOf", `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `1`, ""},
+		{"anyOf mismatch", `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, `true`, "must match at least one schema in anyOf"},
+		{"oneOf matches two", `{"oneOf": [{"type": "integer"}, {"minimum": 0}]}`, `1`, "must match exactly one schema in oneOf, matched 2"},
+		{"not", `{"not": {"type": "string"}}`, `"a"`, "must not match the schema in not"},
+		{"if then", `{"if": {"properties": {"a": {"const": 1}}}, "then": {"required": ["b"]}}`, `{"a": 1}`, `missing required property "b"`},
+		{"if else", `{"if": {"properties": {"a": {"const": 1}}}, "else": {"required": ["c"]}}`, `{"a": 2}`, `missing required property "c"`},
+
+		// $ref
+		{"ref", `{"$defs": {"name": {"type": "string"}}, "properties": {"a": {"$ref": "#/$defs/name"}}}`, `{"a": 1}`, "/a: expected string, got integer"},
+		{"recursive ref", `{"properties": {"child": {"$ref": "#"}, "v": {"type": "integer"}}}`, `{"child": {"child": {"v": "x"}}}`, "/child/child/v: expected integer, got string"},
+
+		// every failure is reported
+		{
+			"multiple errors",
+			`{"properties": {"a": {"type": "string"}, "b": {"type": "integer"}}, "required": ["c"]}`,
+			`{"a": 1, "b": "x"}`,
+			`missing required property "c"; /a: expected string, got integer; /b: expected integer, got string`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(decode(t, tt.schema), decode(t, tt.value))
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() = nil, want %q", tt.want)
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate() returned %T %v, want a *ValidationError", err, err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %q, want %q", err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateErrorPaths(t *testing.T) {
+	err := Validate(decode(t, `{"properties": {"a": {"items": {"required": ["b"]}}}}`), decode(t, `{"a": [{}, {"b": 1}, {}]}`))
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Validate() = %v, want a *ValidationError", err)
+	}
+	want := []Error{
+		{Path: "/a/0", Message: `missing required property "b"`},
+		{Path: "/a/2", Message: `missing required property "b"`},
+	}
+	if !reflect.DeepEqual(validationErr.Errors, want) {
+		t.Errorf("Errors = %+v, want %+v", validationErr.Errors, want)
+	}
+}
+
+func TestValidateInvalidSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{"not a schema", `1`, "must be an object or boolean"},
+		{"bad pattern", `{"pattern": "("}`, "invalid pattern"},
+		{"missing ref", `{"$ref": "#/$defs/missing"}`, `$ref "#/$defs/missing" not found`},
+		{"remote ref", `{"$ref": "https://example.com/schema"}`, "only local references are supported"},
+		{"ref cycle", `{"$defs": {"a": {"$ref": "#/$defs/a"}}, "$ref": "#/$defs/a"}`, "possible $ref cycle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(decode(t, tt.schema), "value")
+			if err == nil {
+				t.Fatalf("Validate() = nil, want %q", tt.want)
+			}
+			if _, ok := err.(*ValidationError); ok {
+				t.Errorf("Validate() returned a *ValidationError for an invalid schema: %v", err)
+			}
+			if !strings.HasPrefix(err.Error(), "invalid schema: ") || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %q, want invalid schema: ...%s", err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateGoValues(t *testing.T) {
+	// Schemas built in Go or decoded from YAML use other numeric and slice types
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"n"},
+		"properties": map[string]interface{}{
+			"n": map[string]interface{}{"type": "integer", "enum": []int{1, 2}},
+		},
+	}
+	if err := Validate(schema, decode(t, `{"n": 2}`)); err != nil {
+		t.Errorf("Validate() = %v, want no error", err)
+	}
+	if err := Validate(schema, decode(t, `{}`)); err == nil {
+		t.Error("Validate() = nil, want missing required property")
+	}
+	if err := Validate(nil, decode(t, `{"any": "thing"}`)); err != nil {
+		t.Errorf("Validate() with a nil schema = %v, want no error", err)
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	schema := decode(t, `{
+		"$defs": {"tag": {"properties": {"color": {"default": "red"}}}},
+		"properties": {
+			"limit": {"type": "integer", "default": 10},
+			"name": {"type": "string", "default": "x"},
+			"options": {"type": "object", "properties": {"verbose": {"default": false}}, "default": {}},
+			"tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}}
+		}
+	}`)
+	got := ApplyDefaults(schema, decode(t, `{"name": "given", "tags": [{}, {"color": "blue"}]}`))
+	want := decode(t, `{
+		"limit": 10,
+		"name": "given",
+		"options": {"verbose": false},
+		"tags": [{"color": "red"}, {"color": "blue"}]
+	}`)
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(want)
+		t.Errorf("ApplyDefaults() = %s, want %s", gotJSON, wantJSON)
+	}
+}
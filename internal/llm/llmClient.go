@@ -3,10 +3,12 @@ package llm
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/credentials"
 	"github.com/isaacphi/slop/internal/domain"
 	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/anthropic"
@@ -24,6 +26,13 @@ type Client struct {
 type MessageResponse struct {
 	TextResponse string
 	ToolCalls    []ToolCall
+	Usage        Usage
+}
+
+// Usage is the number of tokens a model call consumed, zero when the provider does not report it
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
 }
 
 type ToolCall struct {
@@ -32,26 +41,40 @@ type ToolCall struct {
 	Arguments json.RawMessage `json:"arguments"`
 }
 
+// Providers are the supported values of Model.Provider, as in its validate tag
+var Providers = []string{"openai", "anthropic", "googleai"}
+
 func NewClient(modelCfg config.Model) (*Client, error) {
 	var llm llms.Model
 	var err error
 
+	key, err := apiKey(modelCfg)
+	if err != nil {
+		return nil, err
+	}
+
 	switch modelCfg.Provider {
 	case "openai":
-		llm, err = openai.New(
-			openai.WithModel(modelCfg.Name),
-		)
+		opts := []openai.Option{openai.WithModel(modelCfg.Name)}
+		if key != "" {
+			opts = append(opts, openai.WithToken(key))
+		}
+		llm, err = openai.New(opts...)
 	case "anthropic":
-		llm, err = anthropic.New(
-			anthropic.WithModel(modelCfg.Name),
-		)
+		opts := []anthropic.Option{anthropic.WithModel(modelCfg.Name)}
+		if key != "" {
+			opts = append(opts, anthropic.WithToken(key))
+		}
+		llm, err = anthropic.New(opts...)
 	case "googleai":
-		genaiKey := os.Getenv("GEMINI_API_KEY")
+		if key == "" {
+			key = os.Getenv("GEMINI_API_KEY")
+		}
 		ctx := context.Background()
 		llm, err = googleai.New(
 			ctx,
 			googleai.WithDefaultModel(modelCfg.Name),
-			googleai.WithAPIKey(genaiKey),
+			googleai.WithAPIKey(key),
 		)
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", modelCfg.Provider)
@@ -66,6 +89,28 @@ func NewClient(modelCfg config.Model) (*Client, error) {
 	}, nil
 }
 
+// apiKey returns the model's configured key, or the one stored for its
+// provider with slop auth set. Without either it is empty and the provider
+// reads its usual environment variable, e.g. OPENAI_API_KEY.
+func apiKey(modelCfg config.Model) (string, error) {
+	if modelCfg.APIKey.IsSet() {
+		return modelCfg.APIKey.Resolve()
+	}
+
+	store, err := credentials.Default()
+	if err != nil {
+		return "", err
+	}
+	key, err := store.Get(modelCfg.Provider)
+	if errors.Is(err, credentials.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read stored %s key: %w", modelCfg.Provider, err)
+	}
+	return key, nil
+}
+
 func buildMessageHistory(messages []domain.Message) []llms.MessageContent {
 	var history []llms.MessageContent
 	for _, msg := range messages {
@@ -80,74 +125,27 @@ func buildMessageHistory(messages []domain.Message) []llms.MessageContent {
 	return history
 }
 
-func getTools(tools map[string]config.Tool) []llms.Tool {
+// getTools converts tools to langchaingo definitions, passing each tool's
+// JSON Schema through with only the changes the provider requires
+func getTools(provider string, tools map[string]config.Tool) ([]llms.Tool, error) {
 	var result []llms.Tool
 	for name, tool := range tools {
-		paramsMap := convertParameters(tool.Parameters)
+		params, err := sanitizeSchema(provider, tool.JSONSchema())
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: %w", name, err)
+		}
 
 		langchainTool := llms.Tool{
 			Type: "function",
 			Function: &llms.FunctionDefinition{
 				Name:        name,
 				Description: tool.Description,
-				Parameters:  paramsMap,
+				Parameters:  params,
 			},
 		}
 		result = append(result, langchainTool)
 	}
-	return result
-}
-
-func convertParameters(params config.Parameters) map[string]any {
-	properties := make(map[string]any)
-
-	for pName, prop := range params.Properties {
-		properties[pName] = convertProperty(prop)
-	}
-
-	return map[string]any{
-		"type":       params.Type,
-		"properties": properties,
-		"required":   params.Required,
-	}
-}
-
-func convertProperty(prop config.Property) map[string]any {
-	result := map[string]any{
-		"type":        prop.Type,
-		"description": prop.Description,
-	}
-
-	// Add enum if present
-	if len(prop.Enum) > 0 {
-		result["enum"] = prop.Enum
-	}
-
-	// Add default if present
-	if prop.Default != nil {
-		result["default"] = prop.Default
-	}
-
-	// Handle array items
-	if prop.Type == "array" && prop.Items != nil {
-		result["items"] = convertProperty(*prop.Items)
-	}
-
-	// Handle nested object properties
-	if prop.Type == "object" && len(prop.Properties) > 0 {
-		nestedProps := make(map[string]any)
-		for name, p := range prop.Properties {
-			nestedProps[name] = convertProperty(p)
-		}
-		result["properties"] = nestedProps
-
-		// Add required fields for nested object if present
-		if len(prop.Required) > 0 {
-			result["required"] = prop.Required
-		}
-	}
-
-	return result
+	return result, nil
 }
 
 func (c *Client) GetConfig() config.Model {
@@ -166,7 +164,10 @@ func (c *Client) SendMessage(ctx context.Context, content string, history []doma
 	}
 
 	// Convert tools to proper format
-	langchainTools := getTools(tools)
+	langchainTools, err := getTools(c.modelCfg.Provider, tools)
+	if err != nil {
+		return MessageResponse{}, err
+	}
 
 	if len(langchainTools) > 0 {
 		opts = append(opts, llms.WithTools(langchainTools))
@@ -213,5 +214,38 @@ func (c *Client) SendMessage(ctx context.Context, content string, history []doma
 	return MessageResponse{
 		TextResponse: resp.Choices[0].Content,
 		ToolCalls:    toolCalls,
+		Usage:        getUsage(resp.Choices[0].GenerationInfo),
 	}, nil
 }
+
+// getUsage reads token counts from generation info, whose keys differ by provider
+func getUsage(info map[string]any) Usage {
+	var usage Usage
+	for _, key := range []string{"PromptTokens", "InputTokens", "input_tokens"} {
+		if n, ok := toInt(info[key]); ok {
+			usage.InputTokens = n
+			break
+		}
+	}
+	for _, key := range []string{"CompletionTokens", "OutputTokens", "output_tokens"} {
+		if n, ok := toInt(info[key]); ok {
+			usage.OutputTokens = n
+			break
+		}
+	}
+	return usage
+}
+
+func toInt(v any) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
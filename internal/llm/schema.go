@@ -0,0 +1,154 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/isaacphi/slop/internal/jsonschema"
+)
+
+// maxSchemaDepth bounds $ref inlining so recursive schemas terminate
+const maxSchemaDepth = 16
+
+// Keywords the Gemini API accepts in function declarations
+var geminiKeywords = map[string]bool{
+	"type":        true,
+	"format":      true,
+	"description": true,
+	"nullable":    true,
+	"enum":        true,
+	"properties":  true,
+	"required":    true,
+	"items":       true,
+}
+
+// sanitizeSchema adapts a tool's JSON Schema to what a provider accepts.
+// The schema is copied, never modified in place.
+func sanitizeSchema(provider string, schema map[string]interface{}) (map[string]interface{}, error) {
+	schema, err := copySchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every provider expects an object with properties at the top level
+	delete(schema, "$schema")
+	if _, ok := schema["type"]; !ok {
+		schema["type"] = "object"
+	}
+	if _, ok := schema["properties"].(map[string]interface{}); !ok {
+		schema["properties"] = map[string]interface{}{}
+	}
+
+	switch provider {
+	case "googleai":
+		return geminiSchema(schema, schema, 0), nil
+	default:
+		// OpenAI and Anthropic accept JSON Schema as is
+		return schema, nil
+	}
+}
+
+// geminiSchema reduces a schema to the OpenAPI subset Gemini supports:
+// local $refs are inlined, type unions and anyOf/oneOf collapse to their
+// first non-null alternative, and unsupported keywords are dropped
+func geminiSchema(root, schema map[string]interface{}, depth int) map[string]interface{} {
+	if ref, ok := schema["$ref"].(string); ok && depth < maxSchemaDepth {
+		if target, err := jsonschema.ResolveRef(root, ref); err == nil {
+			if targetMap, ok := target.(map[string]interface{}); ok {
+				schema = merge(schema, targetMap)
+			}
+		}
+	}
+
+	for _, key := range []string{"anyOf", "oneOf", "allOf"} {
+		if _, ok := schema["type"]; ok {
+			break
+		}
+		branches, _ := schema[key].([]interface{})
+		for _, branch := range branches {
+			branchMap, ok := branch.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if branchMap["type"] == "null" {
+				schema["nullable"] = true
+				continue
+			}
+			schema = merge(schema, geminiSchema(root, branchMap, depth+1))
+			break
+		}
+	}
+
+	if types, ok := schema["type"].([]interface{}); ok {
+		schema["type"] = "string"
+		for i := len(types) - 1; i >= 0; i-- {
+			if types[i] == "null" {
+				schema["nullable"] = true
+			} else if s, ok := types[i].(string); ok {
+				schema["type"] = s
+			}
+		}
+	}
+
+	result := make(map[string]interface{})
+	for key, value := range schema {
+		if geminiKeywords[key] {
+			result[key] = value
+		}
+	}
+
+	// Gemini only supports string enums
+	if enum, ok := result["enum"].([]interface{}); ok {
+		values := make([]interface{}, len(enum))
+		for i, e := range enum {
+			values[i] = fmt.Sprint(e)
+		}
+		result["enum"] = values
+		result["type"] = "string"
+	}
+
+	if properties, ok := result["properties"].(map[string]interface{}); ok {
+		sanitized := make(map[string]interface{}, len(properties))
+		for name, prop := range properties {
+			if propMap, ok := prop.(map[string]interface{}); ok {
+				sanitized[name] = geminiSchema(root, propMap, depth+1)
+			}
+		}
+		result["properties"] = sanitized
+	}
+	if items, ok := result["items"].(map[string]interface{}); ok {
+		result["items"] = geminiSchema(root, items, depth+1)
+	}
+
+	return result
+}
+
+// merge returns a copy of base with keys from extra that base does not set
+func merge(base, extra map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(base)+len(extra))
+	for k, v := range extra {
+		result[k] = v
+	}
+	for k, v := range base {
+		if k == "$ref" || k == "anyOf" || k == "oneOf" || k == "allOf" {
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+func copySchema(schema map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tool schema: %w", err)
+	}
+	var copied map[string]interface{}
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, fmt.Errorf("invalid tool schema: %w", err)
+	}
+	if copied == nil {
+		copied = make(map[string]interface{})
+	}
+	return copied, nil
+}
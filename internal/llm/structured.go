@@ -0,0 +1,194 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/jsonschema"
+	"github.com/tmc/langchaingo/llms"
+)
+
+const (
+	// Name of the tool used to force structured output from providers without JSON mode
+	structuredTool = "respond"
+	// Extra attempts after a response that does not match the schema
+	maxRepairAttempts = 2
+)
+
+// GenerateStructured asks the model for a JSON value matching schema and
+// returns it, compacted, as the TextResponse. Providers with a JSON mode use
+// it, others are forced to answer through a tool whose parameters are the
+// schema. Both only produce objects, so other schemas are wrapped in an
+// object with a value property. Responses that fail validation are sent back
+// to the model with the errors so it can repair them. An empty schema accepts
+// any JSON value.
+func (c *Client) GenerateStructured(ctx context.Context, content string, history []domain.Message, schema map[string]interface{}) (MessageResponse, error) {
+	if schema == nil {
+		schema = map[string]interface{}{}
+	}
+
+	opts := []llms.CallOption{
+		llms.WithTemperature(c.modelCfg.Temperature),
+		llms.WithMaxTokens(c.modelCfg.MaxTokens),
+	}
+
+	// Only OpenAI honors JSON mode, other providers answer through a tool
+	useTool := c.modelCfg.Provider != "openai"
+	objectSchema, wrapped := toolParameters(schema)
+	if useTool {
+		params, err := sanitizeSchema(c.modelCfg.Provider, objectSchema)
+		if err != nil {
+			return MessageResponse{}, err
+		}
+		opts = append(opts,
+			llms.WithTools([]llms.Tool{{
+				Type: "function",
+				Function: &llms.FunctionDefinition{
+					Name:        structuredTool,
+					Description: "Give your answer as structured data. Always answer by calling this tool.",
+					Parameters:  params,
+				},
+			}}),
+			llms.WithToolChoice(llms.ToolChoice{
+				Type:     "function",
+				Function: &llms.FunctionReference{Name: structuredTool},
+			}),
+		)
+	} else {
+		opts = append(opts, llms.WithJSONMode())
+	}
+
+	// JSON mode answers in text, so ask for the wrapped value there
+	promptSchema := schema
+	if !useTool {
+		promptSchema = objectSchema
+	}
+	schemaJSON, err := json.MarshalIndent(promptSchema, "", "  ")
+	if err != nil {
+		return MessageResponse{}, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	msgs := buildMessageHistory(history)
+	prompt := fmt.Sprintf("%s\n\nRespond with only a JSON value, without any other text, matching this JSON Schema:\n%s", content, schemaJSON)
+	if useTool {
+		prompt = fmt.Sprintf("%s\n\nAnswer by calling the %s tool.", prompt, structuredTool)
+	}
+
+	var usage Usage
+	for attempt := 0; ; attempt++ {
+		msgs = append(msgs, llms.TextParts(llms.ChatMessageTypeHuman, prompt))
+
+		resp, err := c.llm.GenerateContent(ctx, msgs, opts...)
+		if err != nil {
+			return MessageResponse{}, fmt.Errorf("structured message failed: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			return MessageResponse{}, fmt.Errorf("no response choices returned")
+		}
+		u := getUsage(resp.Choices[0].GenerationInfo)
+		usage.InputTokens += u.InputTokens
+		usage.OutputTokens += u.OutputTokens
+
+		reply, raw := structuredResponse(resp.Choices, wrapped)
+		value, err := parseStructured(raw, schema)
+		if err == nil {
+			return MessageResponse{
+				TextResponse: value,
+				Usage:        usage,
+			}, nil
+		}
+		if attempt >= maxRepairAttempts {
+			return MessageResponse{}, fmt.Errorf("response did not match the schema after %d attempts: %w", attempt+1, err)
+		}
+
+		msgs = append(msgs, llms.TextParts(llms.ChatMessageTypeAI, reply))
+		prompt = fmt.Sprintf("That response is invalid: %v\n\nRespond again with only the corrected JSON value.", err)
+	}
+}
+
+// toolParameters wraps schemas that are not objects, since tool parameters
+// and JSON mode responses must be objects. It reports whether the schema was
+// wrapped in a value property.
+func toolParameters(schema map[string]interface{}) (map[string]interface{}, bool) {
+	if schema["type"] == "object" {
+		return schema, false
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"value": schema,
+		},
+		"required": []string{"value"},
+	}, true
+}
+
+// structuredResponse returns the reply of the model, taken from the
+// structured output tool call if there is one and from the text otherwise,
+// and the JSON text of the value in it
+func structuredResponse(choices []*llms.ContentChoice, wrapped bool) (string, string) {
+	for _, choice := range choices {
+		for _, tc := range choice.ToolCalls {
+			if tc.FunctionCall == nil || tc.FunctionCall.Name != structuredTool {
+				continue
+			}
+			return tc.FunctionCall.Arguments, unwrapValue(tc.FunctionCall.Arguments, wrapped)
+		}
+	}
+
+	var text strings.Builder
+	for _, choice := range choices {
+		text.WriteString(choice.Content)
+	}
+	reply := stripCodeFence(text.String())
+	return reply, unwrapValue(reply, wrapped)
+}
+
+// unwrapValue returns the value property of a response to a wrapped schema.
+// An object is never valid for a wrapped schema, so a response that is not
+// wrapped is returned as is.
+func unwrapValue(raw string, wrapped bool) string {
+	if !wrapped {
+		return raw
+	}
+	var obj struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal([]byte(raw), &obj); err == nil && obj.Value != nil {
+		return string(obj.Value)
+	}
+	return raw
+}
+
+// stripCodeFence removes a surrounding ```json fence that models add despite instructions
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if i := strings.Index(s, "\n"); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
+
+// parseStructured checks that raw is JSON matching schema and returns it compacted
+func parseStructured(raw string, schema map[string]interface{}) (string, error) {
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return "", fmt.Errorf("not valid JSON: %w", err)
+	}
+	if err := jsonschema.Validate(schema, value); err != nil {
+		return "", err
+	}
+
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, []byte(raw)); err != nil {
+		return "", err
+	}
+	return compacted.String(), nil
+}
@@ -0,0 +1,247 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"sort"
+	"time"
+)
+
+const (
+	healthCheckInterval = 30 * time.Second
+	pingTimeout         = 5 * time.Second
+	restartBaseDelay    = time.Second
+	restartMaxDelay     = 30 * time.Second
+	maxRestarts         = 5
+)
+
+// ServerState is the lifecycle state of a single MCP server
+type ServerState string
+
+const (
+	StateStarting   ServerState = "starting"
+	StateReady      ServerState = "ready"
+	StateFailed     ServerState = "failed"
+	StateRestarting ServerState = "restarting"
+)
+
+// ServerStatus describes the current state of a single MCP server
+type ServerStatus struct {
+	Name     string      `json:"name"`
+	State    ServerState `json:"state"`
+	Error    string      `json:"error,omitempty"`
+	Restarts int         `json:"restarts"`
+	Tools    int         `json:"tools"`
+}
+
+type serverState struct {
+	state      ServerState
+	lastErr    error
+	restarts   int // Total restarts
+	attempts   int // Restarts since the server was last ready, limited to maxRestarts
+	tools      int
+	restarting bool // A restart loop is already running for this server
+}
+
+// Status returns the state of every configured server, sorted by name
+func (c *Client) Status() []ServerStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	statuses := make([]ServerStatus, 0, len(c.states))
+	for name, s := range c.states {
+		status := ServerStatus{
+			Name:     name,
+			State:    s.state,
+			Restarts: s.restarts,
+			Tools:    s.tools,
+		}
+		if s.lastErr != nil {
+			status.Error = s.lastErr.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+func (c *Client) setState(name string, state ServerState, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.states[name]
+	if !ok {
+		return
+	}
+	s.state = state
+	s.lastErr = err
+}
+
+func (c *Client) getState(name string) ServerState {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if s, ok := c.states[name]; ok {
+		return s.state
+	}
+	return ""
+}
+
+// logStderr copies a server's stderr into the slop log line by line and
+// closes done once it reaches the end
+func logStderr(name string, stderr io.Reader, done chan<- struct{}) {
+	defer close(done)
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		slog.Debug("mcp server stderr", "server", name, "line", scanner.Text())
+	}
+}
+
+// watchProcess waits for a server process to exit and restarts it if it
+// was not stopped on purpose. Wait closes the stderr pipe, so the last lines
+// logged by the server are read before it is called.
+func (c *Client) watchProcess(name string, cmd *exec.Cmd, stderrDone <-chan struct{}) {
+	<-stderrDone
+	err := cmd.Wait()
+
+	c.mu.RLock()
+	current := c.commands[name] == cmd
+	initialized := c.initialized
+	c.mu.RUnlock()
+
+	// Already replaced by a restart
+	if !current {
+		return
+	}
+
+	c.setState(name, StateFailed, fmt.Errorf("process exited: %v", err))
+	// Killed during shutdown
+	if !initialized {
+		return
+	}
+
+	slog.Warn("mcp server exited", "server", name, "error", err)
+	c.restartInBackground(name)
+}
+
+// healthCheck periodically pings every ready server and restarts any that do not answer
+func (c *Client) healthCheck(ctx context.Context) {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, name := range c.serverNames() {
+			// The server may have been restarted since serverNames returned
+			client, ok := c.readyClient(name)
+			if !ok {
+				continue
+			}
+
+			pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+			err := client.Ping(pingCtx)
+			cancel()
+			if err == nil || ctx.Err() != nil {
+				continue
+			}
+
+			slog.Warn("mcp server failed health check", "server", name, "error", err)
+			c.setState(name, StateFailed, err)
+			c.restartInBackground(name)
+		}
+	}
+}
+
+// restartInBackground starts a restart loop for a server unless one is already running
+func (c *Client) restartInBackground(name string) {
+	c.mu.Lock()
+	s, ok := c.states[name]
+	if !ok || s.restarting || c.monitor == nil {
+		c.mu.Unlock()
+		return
+	}
+	s.restarting = true
+	ctx := c.monitor
+	c.mu.Unlock()
+
+	go c.restart(ctx, name)
+}
+
+// restart stops a server and starts it again, backing off exponentially
+// between attempts until it is ready or maxRestarts consecutive attempts fail
+func (c *Client) restart(ctx context.Context, name string) {
+	defer func() {
+		c.mu.Lock()
+		if s, ok := c.states[name]; ok {
+			s.restarting = false
+		}
+		c.mu.Unlock()
+	}()
+
+	delay := restartBaseDelay
+	for {
+		c.mu.Lock()
+		s, ok := c.states[name]
+		if !ok || !c.initialized {
+			c.mu.Unlock()
+			return
+		}
+		if s.attempts >= maxRestarts {
+			c.mu.Unlock()
+			slog.Error("mcp server exceeded restart limit", "server", name, "restarts", maxRestarts)
+			return
+		}
+		s.restarts++
+		s.attempts++
+		s.restarting = true
+		s.state = StateRestarting
+		cmd := c.commands[name]
+		delete(c.commands, name)
+		delete(c.clients, name)
+		c.mu.Unlock()
+
+		if cmd != nil && cmd.Process != nil {
+			_ = cmd.Process.Kill()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		slog.Info("restarting mcp server", "server", name)
+		err := c.startServer(ctx, name, c.servers[name])
+		if err == nil {
+			err = c.loadServerTools(ctx, name)
+		}
+		if err == nil {
+			c.setState(name, StateReady, nil)
+			c.mu.Lock()
+			if s, ok := c.states[name]; ok {
+				s.attempts = 0
+			}
+			c.mu.Unlock()
+			slog.Info("mcp server restarted", "server", name)
+			return
+		}
+
+		slog.Warn("mcp server restart failed", "server", name, "error", err)
+		c.setState(name, StateFailed, err)
+		delay *= 2
+		if delay > restartMaxDelay {
+			delay = restartMaxDelay
+		}
+	}
+}
@@ -3,6 +3,8 @@ package mcp
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"os"
 	"os/exec"
 	"strings"
 	"sync"
@@ -18,9 +20,12 @@ type Client struct {
 	servers     map[string]config.MCPServer
 	clients     map[string]*mcp_golang.Client
 	commands    map[string]*exec.Cmd
+	states      map[string]*serverState
 	tools       map[string]config.Tool
 	mu          sync.RWMutex
 	initialized bool
+	monitor     context.Context // Cancelled on shutdown to stop health checks and restarts
+	cancel      context.CancelFunc
 }
 
 // New creates a new MCP client manager
@@ -29,16 +34,23 @@ func New(servers map[string]config.MCPServer) *Client {
 		servers:  servers,
 		clients:  make(map[string]*mcp_golang.Client),
 		commands: make(map[string]*exec.Cmd),
+		states:   make(map[string]*serverState),
 		tools:    make(map[string]config.Tool),
 	}
 }
 
+// Initialize starts every configured server. Servers that fail to start are
+// reported through Status and retried in the background; an error is only
+// returned if no server could be started at all.
 func (c *Client) Initialize(ctx context.Context) error {
 	c.mu.Lock()
 	if c.initialized {
 		c.mu.Unlock()
 		return errors.New("client already initialized")
 	}
+	for name := range c.servers {
+		c.states[name] = &serverState{state: StateStarting}
+	}
 	c.mu.Unlock()
 
 	var wg sync.WaitGroup
@@ -50,9 +62,17 @@ func (c *Client) Initialize(ctx context.Context) error {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err := c.startServer(ctx, name, server); err != nil {
+			err := c.startServer(ctx, name, server)
+			if err == nil {
+				err = c.loadServerTools(ctx, name)
+			}
+			if err != nil {
+				c.setState(name, StateFailed, err)
+				slog.Warn("mcp server failed to start", "server", name, "error", err)
 				errorsChan <- fmt.Errorf("server %s failed: %w", name, err)
+				return
 			}
+			c.setState(name, StateReady, nil)
 		}()
 	}
 
@@ -60,29 +80,39 @@ func (c *Client) Initialize(ctx context.Context) error {
 	wg.Wait()
 	close(errorsChan)
 
+	var errs []string
 	for err := range errorsChan {
-		return fmt.Errorf("failed to initialize server: %v", err)
+		errs = append(errs, err.Error())
 	}
-
-	if err := c.buildToolRegistry(ctx); err != nil {
-		c.Shutdown()
-		return fmt.Errorf("failed to build tool registry: %w", err)
+	if len(c.servers) > 0 && len(errs) == len(c.servers) {
+		c.killAll()
+		return fmt.Errorf("failed to initialize servers: %s", strings.Join(errs, "; "))
 	}
 
+	monitorCtx, cancel := context.WithCancel(context.Background())
+
 	c.mu.Lock()
 	c.initialized = true
+	c.monitor = monitorCtx
+	c.cancel = cancel
 	c.mu.Unlock()
+
+	// Failed servers are retried in the background like crashed ones
+	for name := range c.servers {
+		if c.getState(name) == StateFailed {
+			c.restartInBackground(name)
+		}
+	}
+	go c.healthCheck(monitorCtx)
+
 	return nil
 }
 
 // startServer starts a single server and establishes its client connection
 func (c *Client) startServer(ctx context.Context, name string, server config.MCPServer) error {
-	cmd := exec.Command(server.Command, server.Args...)
-
-	if server.Env != nil {
-		for k, v := range server.Env {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
-		}
+	cmd, err := buildCommand(server)
+	if err != nil {
+		return err
 	}
 
 	stdin, err := cmd.StdinPipe()
@@ -95,16 +125,26 @@ func (c *Client) startServer(ctx context.Context, name string, server config.MCP
 		return errors.Wrap(err, "failed to get stdout pipe")
 	}
 
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return errors.Wrap(err, "failed to get stderr pipe")
+	}
+
 	if err := cmd.Start(); err != nil {
 		return errors.Wrap(err, "failed to start server")
 	}
 
+	stderrDone := make(chan struct{})
+	go logStderr(name, stderr, stderrDone)
+
 	transport := stdio.NewStdioServerTransportWithIO(stdout, stdin)
 	client := mcp_golang.NewClient(transport)
 
 	// Initialize with client name and version
 	if _, err := client.Initialize(ctx, fmt.Sprintf("slop-%s", name), "1.0.0"); err != nil {
 		_ = cmd.Process.Kill()
+		<-stderrDone
+		_ = cmd.Wait()
 		return errors.Wrap(err, "failed to initialize client")
 	}
 
@@ -113,128 +153,61 @@ func (c *Client) startServer(ctx context.Context, name string, server config.MCP
 	c.commands[name] = cmd
 	c.mu.Unlock()
 
-	return nil
-}
-
-// buildToolRegistry creates a map of all available tools across all servers
-func (c *Client) buildToolRegistry(ctx context.Context) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.tools = make(map[string]config.Tool)
-
-	for serverName, client := range c.clients {
-		response, err := client.ListTools(ctx, nil)
-		if err != nil {
-			return errors.Wrapf(err, "failed to list tools for server %s", serverName)
-		}
-
-		for _, mcpTool := range response.Tools {
-			toolName := fmt.Sprintf("%s__%s", serverName, mcpTool.Name)
-
-			description := ""
-			if mcpTool.Description != nil {
-				description = *mcpTool.Description
-			}
-
-			// Get schema from inputSchema which should be a map[string]interface{}
-			var params config.Parameters
-			if schema, ok := mcpTool.InputSchema.(map[string]interface{}); ok {
-				params = parseSchema(schema)
-			}
-
-			c.tools[toolName] = config.Tool{
-				Name:        toolName,
-				Description: description,
-				Parameters:  params,
-			}
-		}
-	}
+	go c.watchProcess(name, cmd, stderrDone)
 
 	return nil
 }
 
-func parseSchema(schema map[string]interface{}) config.Parameters {
-	params := config.Parameters{
-		Properties: make(map[string]config.Property),
+// loadServerTools adds a server's tools to the registry, replacing any it previously had
+func (c *Client) loadServerTools(ctx context.Context, serverName string) error {
+	c.mu.RLock()
+	client, exists := c.clients[serverName]
+	c.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("server %s not found", serverName)
 	}
 
-	if t, ok := schema["type"].(string); ok {
-		params.Type = t
+	response, err := client.ListTools(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list tools for server %s", serverName)
 	}
 
-	if required, ok := schema["required"].([]interface{}); ok {
-		params.Required = make([]string, 0, len(required))
-		for _, r := range required {
-			if str, ok := r.(string); ok {
-				params.Required = append(params.Required, str)
-			}
-		}
-	}
+	tools := make(map[string]config.Tool)
+	for _, mcpTool := range response.Tools {
+		toolName := fmt.Sprintf("%s__%s", serverName, mcpTool.Name)
 
-	if props, ok := schema["properties"].(map[string]interface{}); ok {
-		for name, propInterface := range props {
-			if propMap, ok := propInterface.(map[string]interface{}); ok {
-				property := parseProperty(propMap)
-				params.Properties[name] = property
-			}
+		description := ""
+		if mcpTool.Description != nil {
+			description = *mcpTool.Description
 		}
-	}
-
-	return params
-}
 
-func parseProperty(propMap map[string]interface{}) config.Property {
-	property := config.Property{}
+		// Keep the raw inputSchema so no keywords are lost
+		schema, _ := mcpTool.InputSchema.(map[string]interface{})
 
-	if t, ok := propMap["type"].(string); ok {
-		property.Type = t
-	}
-
-	if desc, ok := propMap["description"].(string); ok {
-		property.Description = desc
-	}
-
-	if enum, ok := propMap["enum"].([]interface{}); ok {
-		property.Enum = make([]string, 0, len(enum))
-		for _, e := range enum {
-			if str, ok := e.(string); ok {
-				property.Enum = append(property.Enum, str)
-			}
+		tools[toolName] = config.Tool{
+			Name:        toolName,
+			Description: description,
+			InputSchema: schema,
 		}
 	}
 
-	if def, ok := propMap["default"]; ok {
-		property.Default = def
-	}
-
-	// Handle array items
-	if items, ok := propMap["items"].(map[string]interface{}); ok {
-		itemsProp := parseProperty(items)
-		property.Items = &itemsProp
-	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	// Handle nested object properties
-	if props, ok := propMap["properties"].(map[string]interface{}); ok {
-		property.Properties = make(map[string]config.Property)
-		for name, p := range props {
-			if pMap, ok := p.(map[string]interface{}); ok {
-				property.Properties[name] = parseProperty(pMap)
-			}
+	prefix := serverName + "__"
+	for name := range c.tools {
+		if strings.HasPrefix(name, prefix) {
+			delete(c.tools, name)
 		}
 	}
-
-	// Handle nested required fields
-	if required, ok := propMap["required"].([]interface{}); ok {
-		property.Required = make([]string, 0, len(required))
-		for _, r := range required {
-			if str, ok := r.(string); ok {
-				property.Required = append(property.Required, str)
-			}
-		}
+	for name, tool := range tools {
+		c.tools[name] = tool
+	}
+	if s, ok := c.states[serverName]; ok {
+		s.tools = len(tools)
 	}
 
-	return property
+	return nil
 }
 
 // CallTool calls a tool using its fully qualified name (serverName.toolName)
@@ -248,16 +221,22 @@ func (c *Client) CallTool(ctx context.Context, name string, arguments interface{
 
 	c.mu.RLock()
 	client, exists := c.clients[serverName]
+	state, hasState := c.states[serverName]
 	c.mu.RUnlock()
 
-	if !exists {
+	if !exists || !hasState {
 		return nil, fmt.Errorf("server %s not found", serverName)
 	}
+	if state.state != StateReady {
+		return nil, fmt.Errorf("server %s is %s", serverName, state.state)
+	}
 
 	return client.CallTool(ctx, toolName, arguments)
 }
 
-// GetTools returns a map of all available tools
+// GetTools returns the tools of every ready server. Tools of failed or
+// restarting servers are left out so the model is not offered tools that
+// can only fail.
 func (c *Client) GetTools() map[string]config.Tool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -265,7 +244,10 @@ func (c *Client) GetTools() map[string]config.Tool {
 	// Create a copy of the tools map to prevent external modification
 	tools := make(map[string]config.Tool, len(c.tools))
 	for k, v := range c.tools {
-		tools[k] = v
+		serverName, _, _ := strings.Cut(k, "__")
+		if s, ok := c.states[serverName]; ok && s.state == StateReady {
+			tools[k] = v
+		}
 	}
 
 	return tools
@@ -274,32 +256,48 @@ func (c *Client) GetTools() map[string]config.Tool {
 // Shutdown stops all servers and cleans up resources in parallel
 func (c *Client) Shutdown() {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	if !c.initialized {
+		c.mu.Unlock()
 		return
 	}
+	c.initialized = false
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.monitor = nil
+	c.cancel = nil
+	c.mu.Unlock()
 
-	var wg sync.WaitGroup
-	errs := make(chan error, len(c.commands))
+	c.killAll()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commands = make(map[string]*exec.Cmd)
+	c.clients = make(map[string]*mcp_golang.Client)
+	c.states = make(map[string]*serverState)
+	c.tools = make(map[string]config.Tool)
+}
 
+// killAll kills every server process in parallel
+func (c *Client) killAll() {
+	c.mu.RLock()
+	commands := make(map[string]*exec.Cmd, len(c.commands))
 	for name, cmd := range c.commands {
+		commands[name] = cmd
+	}
+	c.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for name, cmd := range commands {
 		if cmd != nil && cmd.Process != nil {
 			wg.Add(1)
 			go func(name string, cmd *exec.Cmd) {
 				defer wg.Done()
-				if err := cmd.Process.Kill(); err != nil {
-					errs <- errors.Wrapf(err, "failed to kill server %s", name)
+				if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+					slog.Warn("failed to kill mcp server", "server", name, "error", err)
 				}
 			}(name, cmd)
 		}
 	}
-
 	wg.Wait()
-	close(errs)
-
-	c.commands = make(map[string]*exec.Cmd)
-	c.clients = make(map[string]*mcp_golang.Client)
-	c.tools = make(map[string]config.Tool)
-	c.initialized = false
 }
@@ -0,0 +1,46 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/config"
+)
+
+// buildCommand prepares the process for a server. The server inherits slop's
+// environment, with configured env entries layered on top. References in
+// args, env and cwd are resolved here so secrets never pass through config.
+func buildCommand(server config.MCPServer) (*exec.Cmd, error) {
+	args := make([]string, 0, len(server.Args))
+	for _, arg := range server.Args {
+		resolved, err := config.ResolveReferences(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve args: %w", err)
+		}
+		args = append(args, resolved)
+	}
+
+	cmd := exec.Command(server.Command, args...)
+	cmd.Env = os.Environ()
+
+	for k, v := range server.Env {
+		resolved, err := config.ResolveReferences(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve env %s: %w", k, err)
+		}
+		// Config keys are lowercased when loaded, and environment variables are conventionally upper case
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", strings.ToUpper(k), resolved))
+	}
+
+	if server.Cwd != "" {
+		cwd, err := config.ResolveReferences(server.Cwd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve cwd: %w", err)
+		}
+		cmd.Dir = config.ExpandHome(cwd)
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,109 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Prompt is a prompt template advertised by one of the MCP servers
+type Prompt struct {
+	Server      string           `json:"server"`
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
+}
+
+// ListPrompts returns the prompts advertised by every server that supports them
+func (c *Client) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	var prompts []Prompt
+
+	for _, serverName := range c.serverNames() {
+		client, ok := c.readyClient(serverName)
+		if !ok {
+			continue
+		}
+		if caps := client.GetCapabilities(); caps == nil || caps.Prompts == nil {
+			continue
+		}
+
+		var cursor *string
+		for {
+			response, err := client.ListPrompts(ctx, cursor)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to list prompts for server %s", serverName)
+			}
+
+			for _, p := range response.Prompts {
+				if p == nil {
+					continue
+				}
+				prompt := Prompt{
+					Server: serverName,
+					Name:   p.Name,
+				}
+				if p.Description != nil {
+					prompt.Description = *p.Description
+				}
+				for _, arg := range p.Arguments {
+					argument := PromptArgument{Name: arg.Name}
+					if arg.Description != nil {
+						argument.Description = *arg.Description
+					}
+					if arg.Required != nil {
+						argument.Required = *arg.Required
+					}
+					prompt.Arguments = append(prompt.Arguments, argument)
+				}
+				prompts = append(prompts, prompt)
+			}
+
+			if response.NextCursor == nil || *response.NextCursor == "" {
+				break
+			}
+			cursor = response.NextCursor
+		}
+	}
+
+	return prompts, nil
+}
+
+// GetPrompt expands a prompt on the given server and returns the text of its messages
+func (c *Client) GetPrompt(ctx context.Context, serverName, promptName string, arguments map[string]string) (string, error) {
+	c.mu.RLock()
+	client, exists := c.clients[serverName]
+	c.mu.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("server %s not found", serverName)
+	}
+
+	if arguments == nil {
+		arguments = make(map[string]string)
+	}
+
+	response, err := client.GetPrompt(ctx, promptName, arguments)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get prompt %s from server %s", promptName, serverName)
+	}
+
+	var parts []string
+	for _, msg := range response.Messages {
+		if msg == nil {
+			continue
+		}
+		if text := contentText(msg.Content); text != "" {
+			parts = append(parts, text)
+		}
+	}
+
+	return strings.Join(parts, "\n\n"), nil
+}
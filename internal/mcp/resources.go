@@ -0,0 +1,223 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	mcp_golang "github.com/metoro-io/mcp-golang"
+	"github.com/pkg/errors"
+)
+
+// Resource is a resource advertised by one of the MCP servers
+type Resource struct {
+	Server      string `json:"server"`
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ListResources returns the resources advertised by every server that supports them
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	var resources []Resource
+
+	for _, serverName := range c.serverNames() {
+		client, ok := c.readyClient(serverName)
+		if !ok {
+			continue
+		}
+		if caps := client.GetCapabilities(); caps == nil || caps.Resources == nil {
+			continue
+		}
+
+		var cursor *string
+		for {
+			response, err := client.ListResources(ctx, cursor)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to list resources for server %s", serverName)
+			}
+
+			for _, r := range response.Resources {
+				if r == nil {
+					continue
+				}
+				resource := Resource{
+					Server: serverName,
+					URI:    r.Uri,
+					Name:   r.Name,
+				}
+				if r.Description != nil {
+					resource.Description = *r.Description
+				}
+				if r.MimeType != nil {
+					resource.MimeType = *r.MimeType
+				}
+				resources = append(resources, resource)
+			}
+
+			if response.NextCursor == nil || *response.NextCursor == "" {
+				break
+			}
+			cursor = response.NextCursor
+		}
+	}
+
+	return resources, nil
+}
+
+// ReadResource reads a resource and returns its text contents.
+// The uri is either a uri advertised by one of the servers, or of the form
+// server://path, in which case path is read from the named server.
+func (c *Client) ReadResource(ctx context.Context, uri string) (string, error) {
+	serverName, serverURI, err := c.resolveResource(ctx, uri)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.RLock()
+	client, exists := c.clients[serverName]
+	c.mu.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("server %s not found", serverName)
+	}
+
+	response, err := client.ReadResource(ctx, serverURI)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read resource %s", uri)
+	}
+	if response == nil {
+		return "", fmt.Errorf("server %s returned no contents for %s", serverName, uri)
+	}
+
+	var contents strings.Builder
+	for i, content := range response.Contents {
+		if content == nil {
+			continue
+		}
+		if i > 0 {
+			contents.WriteString("\n")
+		}
+		switch {
+		case content.TextResourceContents != nil:
+			contents.WriteString(content.TextResourceContents.Text)
+		case content.BlobResourceContents != nil:
+			mimeType := "application/octet-stream"
+			if content.BlobResourceContents.MimeType != nil {
+				mimeType = *content.BlobResourceContents.MimeType
+			}
+			fmt.Fprintf(&contents, "[binary content: %s]", mimeType)
+		}
+	}
+
+	return contents.String(), nil
+}
+
+// resolveResource finds the server responsible for a resource uri and the
+// uri to request from it. Advertised uris are matched first, so that a server
+// named after a scheme does not capture them.
+func (c *Client) resolveResource(ctx context.Context, uri string) (string, string, error) {
+	resources, err := c.ListResources(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	for _, r := range resources {
+		if r.URI == uri {
+			return r.Server, r.URI, nil
+		}
+	}
+
+	// Otherwise server://path is shorthand for path on the named server
+	if serverName, path, ok := strings.Cut(uri, "://"); ok {
+		if _, ready := c.readyClient(serverName); ready {
+			serverURI, err := shorthandURI(serverName, path, resources)
+			if err != nil {
+				return "", "", err
+			}
+			return serverName, serverURI, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("resource %s not found on any server", uri)
+}
+
+// shorthandURI turns the path of a server://path reference into a uri. The
+// path gets the scheme shared by the server's advertised resources, or is made
+// absolute and sent as a file uri.
+func shorthandURI(serverName, path string, resources []Resource) (string, error) {
+	scheme := ""
+	shared := true
+	for _, r := range resources {
+		if r.Server != serverName {
+			continue
+		}
+		s, _, ok := strings.Cut(r.URI, "://")
+		if !ok || (scheme != "" && s != scheme) {
+			shared = false
+			break
+		}
+		scheme = s
+	}
+	if shared && scheme != "" && scheme != "file" {
+		return scheme + "://" + path, nil
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid resource path %s", path)
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String(), nil
+}
+
+// serverNames returns the names of all ready servers in sorted order
+func (c *Client) serverNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.clients))
+	for name := range c.clients {
+		if s, ok := c.states[name]; ok && s.state == StateReady {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// readyClient returns the client of a server if it is ready
+func (c *Client) readyClient(name string) (*mcp_golang.Client, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	client, exists := c.clients[name]
+	if s, ok := c.states[name]; !exists || !ok || s.state != StateReady {
+		return nil, false
+	}
+	return client, true
+}
+
+// contentText flattens MCP content into plain text
+func contentText(content *mcp_golang.Content) string {
+	if content == nil {
+		return ""
+	}
+	switch content.Type {
+	case mcp_golang.ContentTypeText:
+		if content.TextContent != nil {
+			return content.TextContent.Text
+		}
+	case mcp_golang.ContentTypeImage:
+		if content.ImageContent != nil {
+			return fmt.Sprintf("[image: %s]", content.ImageContent.MimeType)
+		}
+	case mcp_golang.ContentTypeEmbeddedResource:
+		if content.EmbeddedResource != nil && content.EmbeddedResource.TextResourceContents != nil {
+			return content.EmbeddedResource.TextResourceContents.Text
+		}
+	}
+	return ""
+}
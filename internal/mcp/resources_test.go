@@ -0,0 +1,45 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShorthandURI(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources := []Resource{
+		{Server: "docs", URI: "docs-store://guides/intro.md"},
+		{Server: "docs", URI: "docs-store://guides/setup.md"},
+		{Server: "mixed", URI: "a://x"},
+		{Server: "mixed", URI: "b://y"},
+		{Server: "files", URI: "file:///srv/a.txt"},
+	}
+	tests := []struct {
+		name   string
+		server string
+		path   string
+		want   string
+	}{
+		{"shared scheme", "docs", "guides/faq.md", "docs-store://guides/faq.md"},
+		{"mixed schemes", "mixed", "/srv/z", "file:///srv/z"},
+		{"file scheme", "files", "/srv/b.txt", "file:///srv/b.txt"},
+		{"no resources", "other", "/srv/c.txt", "file:///srv/c.txt"},
+		{"relative path", "other", "src/main.go", "file://" + filepath.ToSlash(filepath.Join(wd, "src", "main.go"))},
+		{"escaped", "other", "/srv/a b#1", "file:///srv/a%20b%231"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := shorthandURI(tt.server, tt.path, resources)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("shorthandURI(%q, %q) = %q, want %q", tt.server, tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package mcp
+
+import (
+	"context"
+
+	"github.com/isaacphi/slop/internal/config"
+	mcp_golang "github.com/metoro-io/mcp-golang"
+)
+
+// Service is the set of MCP operations used by the rest of slop.
+// It is implemented by Client, which runs servers in-process, and by
+// the daemon client, which talks to servers owned by a background process.
+type Service interface {
+	GetTools() map[string]config.Tool
+	CallTool(ctx context.Context, name string, arguments interface{}) (*mcp_golang.ToolResponse, error)
+	ListResources(ctx context.Context) ([]Resource, error)
+	ReadResource(ctx context.Context, uri string) (string, error)
+	ListPrompts(ctx context.Context) ([]Prompt, error)
+	GetPrompt(ctx context.Context, serverName, promptName string, arguments map[string]string) (string, error)
+	Status() []ServerStatus
+	Shutdown()
+}
+
+var _ Service = (*Client)(nil)
@@ -4,38 +4,26 @@ import (
 	"fmt"
 
 	"github.com/isaacphi/slop/internal/config"
-	"github.com/isaacphi/slop/internal/domain"
-	sqliteRepo "github.com/isaacphi/slop/internal/repository/sqlite"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
+	"github.com/isaacphi/slop/internal/hooks"
+	"github.com/isaacphi/slop/internal/repository/storage"
 )
 
 // InitializeMessageService creates and initializes the message service with all required dependencies
 func InitializeMessageService(cfg *config.ConfigSchema, overrides *MessageServiceOverrides) (*MessageService, error) {
-	// Initialize the database connection
-	db, err := gorm.Open(sqlite.Open(cfg.DBPath), &gorm.Config{})
+	threadRepo, err := storage.Open(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+		return nil, err
 	}
 
-	// AutoMigrate
-	err = db.AutoMigrate(&domain.Thread{}, &domain.Message{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to run migrations: %w", err)
-	}
-
-	// Create the repositories and services
-	threadRepo := sqliteRepo.NewMessageRepository(db)
-
-	modelName := cfg.ActiveModel
+	modelName := cfg.MsgModel()
 	if overrides != nil {
 		if overrides.ActiveModel != nil {
 			modelName = *overrides.ActiveModel
 		}
 	}
-	modelConfig, exists := cfg.Models[modelName]
-	if !exists {
-		return nil, fmt.Errorf("Model %s not found in config", modelName)
+	_, modelConfig, err := cfg.ResolveModel(modelName)
+	if err != nil {
+		return nil, err
 	}
 	if overrides != nil {
 		if overrides.MaxTokens != nil {
@@ -46,7 +34,7 @@ func InitializeMessageService(cfg *config.ConfigSchema, overrides *MessageServic
 		}
 	}
 
-	messageService, err := New(threadRepo, modelConfig)
+	messageService, err := New(threadRepo, modelConfig, hooks.New(cfg.Hooks))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create message service: %w", err)
 	}
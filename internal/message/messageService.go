@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/isaacphi/slop/internal/config"
 	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/hooks"
 	"github.com/isaacphi/slop/internal/llm"
 	"github.com/isaacphi/slop/internal/repository"
 )
@@ -16,9 +17,10 @@ import (
 type MessageService struct {
 	messageRepo repository.MessageRepository
 	llm         *llm.Client
+	hooks       *hooks.Runner
 }
 
-func New(repo repository.MessageRepository, modelCfg config.Model) (*MessageService, error) {
+func New(repo repository.MessageRepository, modelCfg config.Model, hooksRunner *hooks.Runner) (*MessageService, error) {
 
 	llmClient, err := llm.NewClient(modelCfg)
 	if err != nil {
@@ -28,6 +30,7 @@ func New(repo repository.MessageRepository, modelCfg config.Model) (*MessageServ
 	return &MessageService{
 		messageRepo: repo,
 		llm:         llmClient,
+		hooks:       hooksRunner,
 	}, nil
 }
 
@@ -37,6 +40,10 @@ type SendMessageOptions struct {
 	Content       string
 	StreamHandler StreamHandler
 	Tools         map[string]config.Tool
+	ToolFilter    []string // Globs narrowing the tools offered for this message, applied after the model's activeTools
+	// Optional: request a JSON response matching this schema, an empty schema accepts any JSON.
+	// Tools and streaming are not used.
+	Schema map[string]interface{}
 }
 
 func (s *MessageService) SendMessage(ctx context.Context, opts SendMessageOptions) (*domain.Message, error) {
@@ -64,8 +71,20 @@ func (s *MessageService) SendMessage(ctx context.Context, opts SendMessageOption
 		return nil, fmt.Errorf("failed to get conversation history: %w", err)
 	}
 
-	// Create user message
+	// Hooks may veto the message or rewrite its content
 	modelCfg := s.llm.GetConfig()
+	payload, err := s.hooks.Before(ctx, hooks.Payload{
+		Event:    hooks.BeforeSend,
+		ThreadID: opts.ThreadID.String(),
+		Model:    modelCfg.Name,
+		Content:  opts.Content,
+	})
+	if err != nil {
+		return nil, err
+	}
+	opts.Content = payload.Content
+
+	// Create user message
 	userMsg := &domain.Message{
 		ThreadID: opts.ThreadID,
 		ParentID: opts.ParentID,
@@ -105,7 +124,12 @@ func (s *MessageService) SendMessage(ctx context.Context, opts SendMessageOption
 		}
 	}
 
-	aiResponse, err := s.llm.SendMessage(ctx, opts.Content, messages, opts.StreamHandler != nil, streamCallback, opts.Tools)
+	var aiResponse llm.MessageResponse
+	if opts.Schema != nil {
+		aiResponse, err = s.llm.GenerateStructured(ctx, opts.Content, messages, opts.Schema)
+	} else {
+		aiResponse, err = s.llm.SendMessage(ctx, opts.Content, messages, opts.StreamHandler != nil, streamCallback, opts.Tools)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to stream AI response: %w", err)
 	}
@@ -124,6 +148,9 @@ func (s *MessageService) SendMessage(ctx context.Context, opts SendMessageOption
 		ToolCalls: string(toolCallsString),
 		ModelName: modelCfg.Name,
 		Provider:  modelCfg.Provider,
+
+		InputTokens:  aiResponse.Usage.InputTokens,
+		OutputTokens: aiResponse.Usage.OutputTokens,
 	}
 
 	if err := s.messageRepo.AddMessageToThread(ctx, opts.ThreadID, userMsg); err != nil {
@@ -133,12 +160,46 @@ func (s *MessageService) SendMessage(ctx context.Context, opts SendMessageOption
 		return nil, err
 	}
 
+	s.hooks.After(ctx, hooks.Payload{
+		Event:     hooks.AfterSend,
+		ThreadID:  opts.ThreadID.String(),
+		MessageID: aiMsg.ID.String(),
+		Model:     modelCfg.Name,
+		Content:   opts.Content,
+		Response:  aiMsg.Content,
+		ToolCalls: toolCallsString,
+	})
+
 	return aiMsg, nil
 }
 
+// RecordToolExecution adds a tool execution to the audit log
+func (s *MessageService) RecordToolExecution(ctx context.Context, exec *domain.ToolExecution) error {
+	return s.messageRepo.AddToolExecution(ctx, exec)
+}
+
+// UpdateToolExecution saves changes to a recorded tool execution
+func (s *MessageService) UpdateToolExecution(ctx context.Context, exec *domain.ToolExecution) error {
+	return s.messageRepo.UpdateToolExecution(ctx, exec)
+}
+
+// ListToolExecutions returns recorded tool executions matching the filter, newest first
+func (s *MessageService) ListToolExecutions(ctx context.Context, filter domain.ToolExecutionFilter) ([]domain.ToolExecution, error) {
+	return s.messageRepo.ListToolExecutions(ctx, filter)
+}
+
+// GetModelConfig returns the configuration of the model messages are sent to
+func (s *MessageService) GetModelConfig() config.Model {
+	return s.llm.GetConfig()
+}
+
 func (s *MessageService) NewThread(ctx context.Context) (*domain.Thread, error) {
 	thread := &domain.Thread{}
-	return thread, s.messageRepo.CreateThread(ctx, thread)
+	if err := s.messageRepo.CreateThread(ctx, thread); err != nil {
+		return nil, err
+	}
+	s.hooks.After(ctx, hooks.Payload{Event: hooks.ThreadCreate, ThreadID: thread.ID.String()})
+	return thread, nil
 }
 
 func (s *MessageService) GetActiveThread(ctx context.Context) (*domain.Thread, error) {
@@ -207,7 +268,11 @@ func (s *MessageService) DeleteThread(ctx context.Context, threadID uuid.UUID) e
 		return fmt.Errorf("failed to find thread: %w", err)
 	}
 
-	return s.messageRepo.DeleteThread(ctx, threadID)
+	if err := s.messageRepo.DeleteThread(ctx, threadID); err != nil {
+		return err
+	}
+	s.hooks.After(ctx, hooks.Payload{Event: hooks.ThreadDelete, ThreadID: threadID.String()})
+	return nil
 }
 
 // GetThreadMessages returns all messages in a thread
@@ -14,5 +14,9 @@ type StreamHandler interface {
 	HandleMessageDone() error
 	HandleFunctionCallStart(id, name string) error
 	HandleFunctionCallChunk(chunk FunctionCallChunk) error
+	// Called by the agent after it runs a tool the model requested
+	HandleToolResult(id, name, result string, err error) error
+	// Called with the tokens used by each model response
+	HandleUsage(inputTokens, outputTokens int) error
 	Reset()
 }
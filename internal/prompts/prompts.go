@@ -0,0 +1,201 @@
+// Package prompts loads reusable prompt templates.
+//
+// Templates are files in the prompts directory of the global and local config
+// directories, named by their path without extension, e.g.
+// .slop/prompts/git/commit.md is "git/commit". Local templates take
+// precedence over global ones with the same name.
+//
+// Templates use Go's text/template syntax with variables from --var, e.g.
+// {{ .file }}, and these functions:
+//
+//	include "path"   contents of a file, relative to the working directory
+//	shell "command"  output of a shell command
+//
+// A leading {{/* comment */}} is shown as the template's description.
+package prompts
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/isaacphi/slop/internal/config"
+)
+
+const dirName = "prompts"
+
+type Template struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Source      string `json:"source"` // "local" or "global"
+	Description string `json:"description,omitempty"`
+}
+
+// List returns all templates sorted by name
+func List() ([]Template, error) {
+	dirs, err := searchDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]Template)
+	// Later directories take precedence
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir.path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && path == dir.path {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+				return nil
+			}
+			rel, err := filepath.Rel(dir.path, path)
+			if err != nil {
+				return err
+			}
+			name := filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+			byName[name] = Template{
+				Name:        name,
+				Path:        path,
+				Source:      dir.source,
+				Description: description(path),
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt templates in %s: %w", dir.path, err)
+		}
+	}
+
+	templates := make([]Template, 0, len(byName))
+	for _, t := range byName {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+// Find returns the template with the given name
+func Find(name string) (Template, error) {
+	templates, err := List()
+	if err != nil {
+		return Template{}, err
+	}
+	for _, t := range templates {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return Template{}, fmt.Errorf("prompt template %q not found, see slop prompts ls", name)
+}
+
+// Render executes the named template with vars. Referencing a variable that
+// was not given is an error.
+func Render(name string, vars map[string]string) (string, error) {
+	t, err := Find(name)
+	if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(t.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt template: %w", err)
+	}
+
+	tmpl, err := template.New(t.Name).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{
+			"include": include,
+			"shell":   shell,
+		}).
+		Parse(string(content))
+	if err != nil {
+		return "", fmt.Errorf("invalid prompt template %s: %w", t.Path, err)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %s: %w", t.Name, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// ParseVars parses key=value pairs
+func ParseVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid variable %q, expected key=value", pair)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+type searchDir struct {
+	path   string
+	source string
+}
+
+func searchDirs() ([]searchDir, error) {
+	globalDir, err := config.GlobalDir()
+	if err != nil {
+		return nil, err
+	}
+	return []searchDir{
+		{path: filepath.Join(globalDir, dirName), source: "global"},
+		{path: filepath.Join(config.LocalDir(), dirName), source: "local"},
+	}, nil
+}
+
+// description returns the text of a leading {{/* comment */}}
+func description(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	s := strings.TrimSpace(string(content))
+	for _, open := range []string{"{{/*", "{{- /*"} {
+		if !strings.HasPrefix(s, open) {
+			continue
+		}
+		end := strings.Index(s, "*/")
+		if end < 0 {
+			return ""
+		}
+		return strings.Join(strings.Fields(s[len(open):end]), " ")
+	}
+	return ""
+}
+
+func include(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("include %s: %w", path, err)
+	}
+	return string(content), nil
+}
+
+func shell(command string) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("shell %q: %w: %s", command, err, msg)
+		}
+		return "", fmt.Errorf("shell %q: %w", command, err)
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
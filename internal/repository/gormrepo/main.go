@@ -0,0 +1,25 @@
+// Package gormrepo implements repository.MessageRepository with gorm, for
+// every database in internal/repository/storage. Queries stick to SQL that
+// SQLite and PostgreSQL both accept, except where noted.
+package gormrepo
+
+import (
+	"github.com/isaacphi/slop/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type messageRepo struct {
+	db *gorm.DB
+
+	// Case insensitive LIKE, which SQLite's LIKE already is for ASCII
+	like string
+}
+
+func NewMessageRepository(db *gorm.DB) repository.MessageRepository {
+	like := "LIKE"
+	if db.Dialector.Name() == "postgres" {
+		like = "ILIKE"
+	}
+	return &messageRepo{db: db, like: like}
+}
@@ -1,4 +1,4 @@
-package sqlite
+package gormrepo
 
 import (
 	"context"
@@ -85,9 +85,10 @@ func (r *messageRepo) GetMessages(ctx context.Context, threadID uuid.UUID, messa
 			}
 		}
 
-		// Add newest child to our branch and continue with that child
+		// Add newest child to our branch and continue with that child.
+		// Preloaded children do not have their own children loaded.
 		branchMessages[newestChild.ID] = *newestChild
-		current = newestChild
+		current = messageMap[newestChild.ID]
 	}
 
 	// Convert map to slice and sort by creation time
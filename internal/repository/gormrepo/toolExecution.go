@@ -0,0 +1,47 @@
+package gormrepo
+
+import (
+	"context"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/domain"
+)
+
+func (r *messageRepo) AddToolExecution(ctx context.Context, exec *domain.ToolExecution) error {
+	return r.db.WithContext(ctx).Create(exec).Error
+}
+
+func (r *messageRepo) UpdateToolExecution(ctx context.Context, exec *domain.ToolExecution) error {
+	return r.db.WithContext(ctx).Save(exec).Error
+}
+
+func (r *messageRepo) ListToolExecutions(ctx context.Context, filter domain.ToolExecutionFilter) ([]domain.ToolExecution, error) {
+	query := r.db.WithContext(ctx).Order("created_at DESC")
+
+	if filter.ThreadID != nil {
+		query = query.Where("thread_id = ?", *filter.ThreadID)
+	}
+	if filter.MessageID != nil {
+		query = query.Where("message_id = ?", *filter.MessageID)
+	}
+	if filter.Tool != "" {
+		// Translate the glob into a LIKE pattern
+		replacer := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`, "*", "%", "?", "_")
+		query = query.Where(`tool `+r.like+` ? ESCAPE '\'`, replacer.Replace(filter.Tool))
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at <= ?", filter.Until)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var executions []domain.ToolExecution
+	if err := query.Find(&executions).Error; err != nil {
+		return nil, err
+	}
+	return executions, nil
+}
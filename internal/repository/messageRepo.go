@@ -23,4 +23,10 @@ type MessageRepository interface {
 	FindMessageByPartialID(ctx context.Context, threadID uuid.UUID, partialID string) (*domain.Message, error)
 	DeleteLastMessages(ctx context.Context, threadID uuid.UUID, count int) error
 	AddMessageToThread(ctx context.Context, threadID uuid.UUID, msg *domain.Message) error
+
+	// Tool executions
+	AddToolExecution(ctx context.Context, exec *domain.ToolExecution) error
+	UpdateToolExecution(ctx context.Context, exec *domain.ToolExecution) error
+	// Newest first
+	ListToolExecutions(ctx context.Context, filter domain.ToolExecutionFilter) ([]domain.ToolExecution, error)
 }
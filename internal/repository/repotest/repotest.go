@@ -0,0 +1,369 @@
+// Package repotest is a conformance suite for repository.MessageRepository.
+// Every storage backend runs it, see internal/repository/storage.
+package repotest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
+)
+
+// Run runs the suite. open must return an empty repository for each test.
+func Run(t *testing.T, open func(t *testing.T) repository.MessageRepository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, repo repository.MessageRepository)
+	}{
+		{"CreateThread", testCreateThread},
+		{"ListThreads", testListThreads},
+		{"GetMessages", testGetMessages},
+		{"DeleteLastMessages", testDeleteLastMessages},
+		{"DeleteThread", testDeleteThread},
+		{"PartialID", testPartialID},
+		{"ListToolExecutions", testListToolExecutions},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, open(t))
+		})
+	}
+}
+
+// base is the creation time of test records. Times are set explicitly so
+// that ordering does not depend on the clock resolution of the database.
+var base = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func at(i int) time.Time {
+	return base.Add(time.Duration(i) * time.Second)
+}
+
+func createThread(t *testing.T, repo repository.MessageRepository, i int) *domain.Thread {
+	t.Helper()
+	thread := &domain.Thread{}
+	thread.CreatedAt = at(i)
+	if err := repo.CreateThread(context.Background(), thread); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	return thread
+}
+
+func addMessage(t *testing.T, repo repository.MessageRepository, threadID uuid.UUID, parent *domain.Message, i int, content string) *domain.Message {
+	t.Helper()
+	msg := &domain.Message{Role: domain.RoleHuman, Content: content}
+	if parent != nil {
+		msg.ParentID = &parent.ID
+		if parent.Role == domain.RoleHuman {
+			msg.Role = domain.RoleAssistant
+		}
+	}
+	msg.CreatedAt = at(i)
+	if err := repo.AddMessageToThread(context.Background(), threadID, msg); err != nil {
+		t.Fatalf("AddMessageToThread: %v", err)
+	}
+	return msg
+}
+
+func contents(messages []domain.Message) string {
+	var parts []string
+	for _, msg := range messages {
+		parts = append(parts, msg.Content)
+	}
+	return strings.Join(parts, ",")
+}
+
+func testCreateThread(t *testing.T, repo repository.MessageRepository) {
+	ctx := context.Background()
+	thread := createThread(t, repo, 0)
+	if thread.ID == uuid.Nil {
+		t.Fatal("CreateThread did not assign an ID")
+	}
+
+	got, err := repo.GetThreadByID(ctx, thread.ID)
+	if err != nil {
+		t.Fatalf("GetThreadByID: %v", err)
+	}
+	if got.ID != thread.ID {
+		t.Errorf("GetThreadByID returned %s, want %s", got.ID, thread.ID)
+	}
+
+	if err := repo.SetThreadSummary(ctx, thread.ID, "a summary"); err != nil {
+		t.Fatalf("SetThreadSummary: %v", err)
+	}
+	if got, _ := repo.GetThreadByID(ctx, thread.ID); got == nil || got.Summary != "a summary" {
+		t.Errorf("summary was not saved: %+v", got)
+	}
+
+	if _, err := repo.GetThreadByID(ctx, uuid.New()); !domain.IsNoConversationError(err) {
+		t.Errorf("GetThreadByID of an unknown thread returned %v, want NoConversationError", err)
+	}
+}
+
+func testListThreads(t *testing.T, repo repository.MessageRepository) {
+	ctx := context.Background()
+	if _, err := repo.GetMostRecentThread(ctx); !domain.IsNoConversationError(err) {
+		t.Errorf("GetMostRecentThread without threads returned %v, want NoConversationError", err)
+	}
+
+	first := createThread(t, repo, 0)
+	second := createThread(t, repo, 1)
+	third := createThread(t, repo, 2)
+
+	threads, err := repo.ListThreads(ctx, 0)
+	if err != nil {
+		t.Fatalf("ListThreads: %v", err)
+	}
+	want := []uuid.UUID{third.ID, second.ID, first.ID}
+	if len(threads) != len(want) {
+		t.Fatalf("ListThreads returned %d threads, want %d", len(threads), len(want))
+	}
+	for i, thread := range threads {
+		if thread.ID != want[i] {
+			t.Errorf("ListThreads()[%d] = %s, want %s", i, thread.ID, want[i])
+		}
+	}
+
+	if threads, err := repo.ListThreads(ctx, 2); err != nil || len(threads) != 2 {
+		t.Errorf("ListThreads with a limit of 2 returned %d threads, %v", len(threads), err)
+	}
+
+	recent, err := repo.GetMostRecentThread(ctx)
+	if err != nil {
+		t.Fatalf("GetMostRecentThread: %v", err)
+	}
+	if recent.ID != third.ID {
+		t.Errorf("GetMostRecentThread returned %s, want %s", recent.ID, third.ID)
+	}
+}
+
+// branches creates a thread with two branches after the second message:
+//
+//	a - b - c
+//	      \ d - e
+func branches(t *testing.T, repo repository.MessageRepository) (*domain.Thread, map[string]*domain.Message) {
+	t.Helper()
+	thread := createThread(t, repo, 0)
+	msgs := make(map[string]*domain.Message)
+	msgs["a"] = addMessage(t, repo, thread.ID, nil, 1, "a")
+	msgs["b"] = addMessage(t, repo, thread.ID, msgs["a"], 2, "b")
+	msgs["c"] = addMessage(t, repo, thread.ID, msgs["b"], 3, "c")
+	msgs["d"] = addMessage(t, repo, thread.ID, msgs["b"], 4, "d")
+	msgs["e"] = addMessage(t, repo, thread.ID, msgs["d"], 5, "e")
+	return thread, msgs
+}
+
+func testGetMessages(t *testing.T, repo repository.MessageRepository) {
+	ctx := context.Background()
+	thread, msgs := branches(t, repo)
+
+	tests := []struct {
+		name   string
+		from   string
+		future bool
+		want   string
+	}{
+		{"newest", "", false, "a,b,d,e"},
+		{"up to message", "c", false, "a,b,c"},
+		{"up to branch point", "b", false, "a,b"},
+		{"newest children", "b", true, "a,b,d,e"},
+		{"leaf with future", "c", true, "a,b,c"},
+		{"root with future", "a", true, "a,b,d,e"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var from *uuid.UUID
+			if tt.from != "" {
+				from = &msgs[tt.from].ID
+			}
+			got, err := repo.GetMessages(ctx, thread.ID, from, tt.future)
+			if err != nil {
+				t.Fatalf("GetMessages: %v", err)
+			}
+			if contents(got) != tt.want {
+				t.Errorf("GetMessages returned %s, want %s", contents(got), tt.want)
+			}
+		})
+	}
+
+	if _, err := repo.GetMessages(ctx, thread.ID, &thread.ID, false); err == nil {
+		t.Error("GetMessages from an unknown message did not return an error")
+	}
+
+	other := createThread(t, repo, 10)
+	if got, err := repo.GetMessages(ctx, other.ID, nil, false); err != nil || len(got) != 0 {
+		t.Errorf("GetMessages of an empty thread returned %s, %v", contents(got), err)
+	}
+}
+
+func testDeleteLastMessages(t *testing.T, repo repository.MessageRepository) {
+	ctx := context.Background()
+	thread, msgs := branches(t, repo)
+	other := createThread(t, repo, 10)
+	addMessage(t, repo, other.ID, nil, 11, "x")
+
+	if err := repo.DeleteLastMessages(ctx, thread.ID, 2); err != nil {
+		t.Fatalf("DeleteLastMessages: %v", err)
+	}
+	got, err := repo.GetMessages(ctx, thread.ID, nil, false)
+	if err != nil {
+		t.Fatalf("GetMessages: %v", err)
+	}
+	if contents(got) != "a,b,c" {
+		t.Errorf("after deleting 2 messages GetMessages returned %s, want a,b,c", contents(got))
+	}
+	if _, err := repo.GetMessages(ctx, thread.ID, &msgs["e"].ID, false); err == nil {
+		t.Error("deleted message is still returned")
+	}
+
+	if err := repo.DeleteLastMessages(ctx, thread.ID, 10); err != nil {
+		t.Fatalf("DeleteLastMessages: %v", err)
+	}
+	if got, _ := repo.GetMessages(ctx, thread.ID, nil, false); len(got) != 0 {
+		t.Errorf("after deleting every message GetMessages returned %s", contents(got))
+	}
+
+	if got, _ := repo.GetMessages(ctx, other.ID, nil, false); contents(got) != "x" {
+		t.Errorf("messages of another thread were deleted, got %s", contents(got))
+	}
+}
+
+func testDeleteThread(t *testing.T, repo repository.MessageRepository) {
+	ctx := context.Background()
+	thread, _ := branches(t, repo)
+	other := createThread(t, repo, 10)
+	addMessage(t, repo, other.ID, nil, 11, "x")
+
+	if err := repo.DeleteThread(ctx, thread.ID); err != nil {
+		t.Fatalf("DeleteThread: %v", err)
+	}
+	if _, err := repo.GetThreadByID(ctx, thread.ID); !domain.IsNoConversationError(err) {
+		t.Errorf("GetThreadByID of a deleted thread returned %v, want NoConversationError", err)
+	}
+	if got, _ := repo.GetMessages(ctx, thread.ID, nil, false); len(got) != 0 {
+		t.Errorf("messages of a deleted thread are still returned: %s", contents(got))
+	}
+
+	threads, err := repo.ListThreads(ctx, 0)
+	if err != nil {
+		t.Fatalf("ListThreads: %v", err)
+	}
+	if len(threads) != 1 || threads[0].ID != other.ID {
+		t.Errorf("ListThreads after DeleteThread returned %d threads, want only %s", len(threads), other.ID)
+	}
+	if got, _ := repo.GetMessages(ctx, other.ID, nil, false); contents(got) != "x" {
+		t.Errorf("messages of another thread were deleted, got %s", contents(got))
+	}
+}
+
+func testPartialID(t *testing.T, repo repository.MessageRepository) {
+	ctx := context.Background()
+	thread, msgs := branches(t, repo)
+	other := createThread(t, repo, 10)
+
+	prefix := strings.ToUpper(thread.ID.String()[:8])
+	got, err := repo.GetThreadByPartialID(ctx, prefix)
+	if err != nil {
+		t.Fatalf("GetThreadByPartialID(%s): %v", prefix, err)
+	}
+	if got.ID != thread.ID {
+		t.Errorf("GetThreadByPartialID(%s) returned %s, want %s", prefix, got.ID, thread.ID)
+	}
+	if len(got.Messages) != len(msgs) {
+		t.Errorf("GetThreadByPartialID loaded %d messages, want %d", len(got.Messages), len(msgs))
+	}
+
+	if _, err := repo.GetThreadByPartialID(ctx, "not-an-id"); !domain.IsNoConversationError(err) {
+		t.Errorf("GetThreadByPartialID of an unknown ID returned %v, want NoConversationError", err)
+	}
+
+	want := msgs["d"]
+	msg, err := repo.FindMessageByPartialID(ctx, thread.ID, strings.ToUpper(want.ID.String()[:8]))
+	if err != nil {
+		t.Fatalf("FindMessageByPartialID: %v", err)
+	}
+	if msg.ID != want.ID {
+		t.Errorf("FindMessageByPartialID returned %s, want %s", msg.ID, want.ID)
+	}
+	if _, err := repo.FindMessageByPartialID(ctx, other.ID, want.ID.String()[:8]); err == nil {
+		t.Error("FindMessageByPartialID found a message of another thread")
+	}
+}
+
+func testListToolExecutions(t *testing.T, repo repository.MessageRepository) {
+	ctx := context.Background()
+	thread, msgs := branches(t, repo)
+
+	tools := []string{
+		"filesystem__read_file",
+		"filesystem__write_file",
+		"filesystemX_read",
+		"GitHub__search",
+		"native__100%",
+		"native__1000",
+	}
+	for i, tool := range tools {
+		exec := &domain.ToolExecution{
+			ThreadID:  thread.ID,
+			MessageID: msgs["b"].ID,
+			Tool:      tool,
+			Approval:  domain.ApprovalPending,
+		}
+		exec.CreatedAt = at(i)
+		if err := repo.AddToolExecution(ctx, exec); err != nil {
+			t.Fatalf("AddToolExecution: %v", err)
+		}
+		if i == 0 {
+			exec.Approval = domain.ApprovalApproved
+			exec.Result = "ok"
+			if err := repo.UpdateToolExecution(ctx, exec); err != nil {
+				t.Fatalf("UpdateToolExecution: %v", err)
+			}
+		}
+	}
+
+	messageID := msgs["d"].ID
+	tests := []struct {
+		name   string
+		filter domain.ToolExecutionFilter
+		want   string
+	}{
+		{"all newest first", domain.ToolExecutionFilter{}, "native__1000,native__100%,GitHub__search,filesystemX_read,filesystem__write_file,filesystem__read_file"},
+		{"limit", domain.ToolExecutionFilter{Limit: 2}, "native__1000,native__100%"},
+		{"glob", domain.ToolExecutionFilter{Tool: "filesystem__*"}, "filesystem__write_file,filesystem__read_file"},
+		{"case insensitive", domain.ToolExecutionFilter{Tool: "github__*"}, "GitHub__search"},
+		{"single character", domain.ToolExecutionFilter{Tool: "GITHUB__?earch"}, "GitHub__search"},
+		{"literal percent", domain.ToolExecutionFilter{Tool: "native__100%"}, "native__100%"},
+		{"exact", domain.ToolExecutionFilter{Tool: "filesystem__read_file"}, "filesystem__read_file"},
+		{"since", domain.ToolExecutionFilter{Since: at(4)}, "native__1000,native__100%"},
+		{"until", domain.ToolExecutionFilter{Until: at(1)}, "filesystem__write_file,filesystem__read_file"},
+		{"thread", domain.ToolExecutionFilter{ThreadID: &thread.ID, Tool: "native__*"}, "native__1000,native__100%"},
+		{"message", domain.ToolExecutionFilter{MessageID: &messageID}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execs, err := repo.ListToolExecutions(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("ListToolExecutions: %v", err)
+			}
+			var got []string
+			for _, exec := range execs {
+				got = append(got, exec.Tool)
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("ListToolExecutions returned %s, want %s", strings.Join(got, ","), tt.want)
+			}
+		})
+	}
+
+	execs, err := repo.ListToolExecutions(ctx, domain.ToolExecutionFilter{Tool: "filesystem__read_file"})
+	if err != nil || len(execs) != 1 {
+		t.Fatalf("ListToolExecutions returned %d executions, %v", len(execs), err)
+	}
+	if execs[0].Approval != domain.ApprovalApproved || execs[0].Result != "ok" {
+		t.Errorf("UpdateToolExecution was not saved: %+v", execs[0])
+	}
+}
@@ -0,0 +1,66 @@
+// Package storage opens the database configured in the storage config block.
+package storage
+
+import (
+	"fmt"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/repository"
+	"github.com/isaacphi/slop/internal/repository/gormrepo"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Open connects to the configured database, migrates it and returns a
+// repository for it. SQLite uses dbPath unless a DSN is given.
+func Open(cfg *config.ConfigSchema) (repository.MessageRepository, error) {
+	db, err := openDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return gormrepo.NewMessageRepository(db), nil
+}
+
+// openDB connects to and migrates the configured database
+func openDB(cfg *config.ConfigSchema) (*gorm.DB, error) {
+	dsn, err := cfg.Storage.ResolveDSN()
+	if err != nil {
+		return nil, err
+	}
+
+	var dialector gorm.Dialector
+	switch cfg.Storage.Driver {
+	case "", DriverSQLite:
+		if dsn == "" {
+			dsn = cfg.DBPath
+		}
+		dialector = sqlite.Open(dsn)
+	case DriverPostgres:
+		if dsn == "" {
+			return nil, fmt.Errorf("storage.dsn is required for the postgres driver")
+		}
+		dialector = postgres.Open(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s", cfg.Storage.Driver)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// AutoMigrate
+	err = db.AutoMigrate(&domain.Thread{}, &domain.Message{}, &domain.ToolExecution{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return db, nil
+}
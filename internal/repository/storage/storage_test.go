@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"os"
+	"testing"
+
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/repository"
+	"github.com/isaacphi/slop/internal/repository/gormrepo"
+	"github.com/isaacphi/slop/internal/repository/repotest"
+)
+
+// postgresDSNEnv points the suite at an empty PostgreSQL database. Its tables
+// are truncated before each test.
+const postgresDSNEnv = "SLOP_TEST_POSTGRES_DSN"
+
+func TestSQLite(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.MessageRepository {
+		db, err := openDB(&config.ConfigSchema{Storage: config.Storage{Driver: DriverSQLite, DSN: ":memory:"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Each connection to :memory: opens a new database
+		sqlDB.SetMaxOpenConns(1)
+		t.Cleanup(func() { sqlDB.Close() })
+		return gormrepo.NewMessageRepository(db)
+	})
+}
+
+func TestPostgres(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("set %s to run against PostgreSQL", postgresDSNEnv)
+	}
+	repotest.Run(t, func(t *testing.T) repository.MessageRepository {
+		db, err := openDB(&config.ConfigSchema{Storage: config.Storage{Driver: DriverPostgres, DSN: dsn}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Exec("TRUNCATE threads, messages, tool_executions CASCADE").Error; err != nil {
+			t.Fatal(err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { sqlDB.Close() })
+		return gormrepo.NewMessageRepository(db)
+	})
+}
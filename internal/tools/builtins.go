@@ -0,0 +1,368 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/isaacphi/slop/internal/config"
+)
+
+const (
+	maxReadBytes    = 256 * 1024
+	maxOutputBytes  = 64 * 1024
+	maxGrepMatches  = 200
+	maxGrepFileSize = 1024 * 1024
+	commandTimeout  = 2 * time.Minute
+)
+
+type builtin struct {
+	tool    config.Tool
+	handler func(r *Registry) handler
+}
+
+var builtins = map[string]builtin{
+	"read_file": {
+		tool: config.Tool{
+			Description: "Read the contents of a text file",
+			Parameters: config.Parameters{
+				Type: "object",
+				Properties: map[string]config.Property{
+					"path": {Type: "string", Description: "Path of the file to read"},
+				},
+				Required: []string{"path"},
+			},
+		},
+		handler: func(r *Registry) handler { return r.readFile },
+	},
+	"list_dir": {
+		tool: config.Tool{
+			Description: "List the entries of a directory. Directories end with /",
+			Parameters: config.Parameters{
+				Type: "object",
+				Properties: map[string]config.Property{
+					"path": {Type: "string", Description: "Path of the directory to list"},
+				},
+				Required: []string{"path"},
+			},
+		},
+		handler: func(r *Registry) handler { return r.listDir },
+	},
+	"grep": {
+		tool: config.Tool{
+			Description: "Search files for lines matching a regular expression. Returns path:line: text for each match",
+			Parameters: config.Parameters{
+				Type: "object",
+				Properties: map[string]config.Property{
+					"pattern": {Type: "string", Description: "Regular expression (Go RE2 syntax)"},
+					"path":    {Type: "string", Description: "File or directory to search, defaults to the first root"},
+					"glob":    {Type: "string", Description: "Only search files whose name matches this glob, e.g. *.go"},
+				},
+				Required: []string{"pattern"},
+			},
+		},
+		handler: func(r *Registry) handler { return r.grep },
+	},
+	"write_file": {
+		tool: config.Tool{
+			Description: "Create or overwrite a file with the given content",
+			Parameters: config.Parameters{
+				Type: "object",
+				Properties: map[string]config.Property{
+					"path":    {Type: "string", Description: "Path of the file to write"},
+					"content": {Type: "string", Description: "Full content of the file"},
+				},
+				Required: []string{"path", "content"},
+			},
+		},
+		handler: func(r *Registry) handler { return r.writeFile },
+	},
+	"run_command": {
+		tool: config.Tool{
+			Description: "Run an allowed command in the first root directory and return its combined output",
+			Parameters: config.Parameters{
+				Type: "object",
+				Properties: map[string]config.Property{
+					"command": {Type: "string", Description: "Executable to run"},
+					"args":    {Type: "array", Description: "Arguments", Items: &config.Property{Type: "string"}},
+				},
+				Required: []string{"command"},
+			},
+		},
+		handler: func(r *Registry) handler { return r.runCommand },
+	},
+}
+
+func (r *Registry) readFile(ctx context.Context, args map[string]interface{}) (string, error) {
+	path, err := stringArg(args, "path")
+	if err != nil {
+		return "", err
+	}
+	resolved, err := r.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(resolved)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxReadBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxReadBytes {
+		return string(data[:maxReadBytes]) + "\n[truncated]", nil
+	}
+	return string(data), nil
+}
+
+func (r *Registry) listDir(ctx context.Context, args map[string]interface{}) (string, error) {
+	path, err := stringArg(args, "path")
+	if err != nil {
+		return "", err
+	}
+	resolved, err := r.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := os.ReadDir(resolved)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	for _, entry := range entries {
+		out.WriteString(entry.Name())
+		if entry.IsDir() {
+			out.WriteString("/")
+		}
+		out.WriteString("\n")
+	}
+	return out.String(), nil
+}
+
+func (r *Registry) grep(ctx context.Context, args map[string]interface{}) (string, error) {
+	pattern, err := stringArg(args, "pattern")
+	if err != nil {
+		return "", err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	path, _ := args["path"].(string)
+	if path == "" {
+		if len(r.roots) == 0 {
+			return "", fmt.Errorf("no tool roots configured")
+		}
+		path = r.roots[0]
+	}
+	root, err := r.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	glob, _ := args["glob"].(string)
+
+	var out strings.Builder
+	matches := 0
+	errLimit := errors.New("match limit reached")
+
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" || d.Name() == "node_modules" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if glob != "" {
+			if ok, _ := filepath.Match(glob, d.Name()); !ok {
+				return nil
+			}
+		}
+		// WalkDir does not follow symlinks but os.Open does, so links must
+		// point inside the roots like any other path
+		target := p
+		if d.Type()&fs.ModeSymlink != 0 {
+			if target, err = r.resolvePath(p); err != nil {
+				return nil
+			}
+		}
+		if info, err := os.Stat(target); err != nil || !info.Mode().IsRegular() || info.Size() > maxGrepFileSize {
+			return nil
+		}
+
+		f, err := os.Open(target)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		lineNum := 0
+		for scanner.Scan() {
+			lineNum++
+			line := scanner.Text()
+			if strings.ContainsRune(line, 0) {
+				// Binary file
+				return nil
+			}
+			if re.MatchString(line) {
+				fmt.Fprintf(&out, "%s:%d: %s\n", p, lineNum, line)
+				matches++
+				if matches >= maxGrepMatches {
+					return errLimit
+				}
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errLimit) {
+		out.WriteString("[more matches omitted]\n")
+	} else if err != nil {
+		return "", err
+	}
+
+	if matches == 0 {
+		return "no matches", nil
+	}
+	return out.String(), nil
+}
+
+func (r *Registry) writeFile(ctx context.Context, args map[string]interface{}) (string, error) {
+	path, err := stringArg(args, "path")
+	if err != nil {
+		return "", err
+	}
+	content, err := stringArg(args, "content")
+	if err != nil {
+		return "", err
+	}
+	resolved, err := r.resolvePath(path)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(resolved), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(resolved, []byte(content), 0644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("wrote %d bytes to %s", len(content), resolved), nil
+}
+
+func (r *Registry) runCommand(ctx context.Context, args map[string]interface{}) (string, error) {
+	command, err := stringArg(args, "command")
+	if err != nil {
+		return "", err
+	}
+
+	allowed := false
+	for _, c := range r.commands {
+		if c == command {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return "", fmt.Errorf("command %s is not allowed, allowed commands are: %v", command, r.commands)
+	}
+
+	var cmdArgs []string
+	if rawArgs, ok := args["args"].([]interface{}); ok {
+		for _, a := range rawArgs {
+			cmdArgs = append(cmdArgs, fmt.Sprint(a))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command, cmdArgs...)
+	if len(r.roots) > 0 {
+		cmd.Dir = r.roots[0]
+	}
+	output, err := cmd.CombinedOutput()
+	result := truncate(string(output))
+	if err != nil {
+		return result, fmt.Errorf("command failed: %w\n%s", err, result)
+	}
+	return result, nil
+}
+
+// resolvePath makes path absolute, relative to the first root, and checks
+// that it does not escape the configured roots, including through symlinks
+func (r *Registry) resolvePath(path string) (string, error) {
+	if len(r.roots) == 0 {
+		return "", fmt.Errorf("no tool roots configured")
+	}
+
+	path = config.ExpandHome(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.roots[0], path)
+	}
+	path = filepath.Clean(path)
+
+	// Resolve symlinks on the longest existing prefix so new files can be checked too
+	resolved := path
+	var missing []string
+	for {
+		if p, err := filepath.EvalSymlinks(resolved); err == nil {
+			resolved = filepath.Join(append([]string{p}, missing...)...)
+			break
+		}
+		parent := filepath.Dir(resolved)
+		if parent == resolved {
+			break
+		}
+		missing = append([]string{filepath.Base(resolved)}, missing...)
+		resolved = parent
+	}
+
+	for _, root := range r.roots {
+		rel, err := filepath.Rel(root, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("path %s is outside of the allowed roots %v", path, r.roots)
+}
+
+func stringArg(args map[string]interface{}, name string) (string, error) {
+	v, ok := args[name]
+	if !ok {
+		return "", fmt.Errorf("missing required argument: %s", name)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("argument %s must be a string", name)
+	}
+	return s, nil
+}
+
+func truncate(s string) string {
+	if len(s) > maxOutputBytes {
+		return s[:maxOutputBytes] + "\n[truncated]"
+	}
+	return s
+}
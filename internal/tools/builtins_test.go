@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/isaacphi/slop/internal/config"
+)
+
+func TestResolvePath(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(dir, "root")
+	outside := filepath.Join(dir, "outside")
+	for _, d := range []string{filepath.Join(root, "sub"), outside, root + "2"} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{filepath.Join(root, "sub", "a.txt"), filepath.Join(outside, "secret")} {
+		if err := os.WriteFile(f, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{
+		filepath.Join(root, "in"):  filepath.Join(root, "sub"),
+		filepath.Join(root, "out"): outside,
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks unavailable: %v", err)
+		}
+	}
+
+	r, err := New(config.NativeTools{Roots: []string{root}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string // Resolved path, empty if the path must be rejected
+	}{
+		{"relative", "sub/a.txt", filepath.Join(root, "sub", "a.txt")},
+		{"absolute", filepath.Join(root, "sub", "a.txt"), filepath.Join(root, "sub", "a.txt")},
+		{"root itself", ".", root},
+		{"dot dot inside", "sub/../sub/a.txt", filepath.Join(root, "sub", "a.txt")},
+		{"new file", "sub/new.txt", filepath.Join(root, "sub", "new.txt")},
+		{"new directories", "x/y/z.txt", filepath.Join(root, "x", "y", "z.txt")},
+		{"symlink inside", "in/a.txt", filepath.Join(root, "sub", "a.txt")},
+		{"new file under symlink inside", "in/new.txt", filepath.Join(root, "sub", "new.txt")},
+		{"dot dot escape", "../outside/secret", ""},
+		{"nested dot dot escape", "sub/../../outside/secret", ""},
+		{"absolute outside", filepath.Join(outside, "secret"), ""},
+		{"sibling with root as prefix", filepath.Join(root+"2", "f"), ""},
+		{"symlink outside", "out/secret", ""},
+		{"symlink outside itself", "out", ""},
+		{"new file under symlink outside", "out/new.txt", ""},
+		{"dot dot through symlink", "in/../../outside/secret", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.resolvePath(tt.path)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("resolvePath(%q) = %q, want an error", tt.path, got)
+				}
+				if !strings.Contains(err.Error(), "outside of the allowed roots") {
+					t.Errorf("resolvePath(%q) error = %v, want outside of the allowed roots", tt.path, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolvePath(%q) = %v, want %q", tt.path, err, tt.want)
+			}
+			if got != tt.want {
+				t.Errorf("resolvePath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWithoutRoots(t *testing.T) {
+	r, err := New(config.NativeTools{Enabled: []string{"read_file", "grep"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tools := r.GetTools(); len(tools) != 0 {
+		t.Errorf("GetTools() = %v, want no file tools without roots", tools)
+	}
+	if _, err := r.resolvePath("a.txt"); err == nil {
+		t.Error("resolvePath() = nil error, want no tool roots configured")
+	}
+}
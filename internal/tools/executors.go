@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// commandHandler runs a shell command for a config-declared tool. The
+// arguments are passed as JSON on stdin, and each top level argument is
+// also available as SLOP_ARG_<NAME>.
+func commandHandler(command string) handler {
+	return func(ctx context.Context, args map[string]interface{}) (string, error) {
+		input, err := json.Marshal(args)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode arguments: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Env = os.Environ()
+		for k, v := range args {
+			value, ok := v.(string)
+			if !ok {
+				encoded, _ := json.Marshal(v)
+				value = string(encoded)
+			}
+			cmd.Env = append(cmd.Env, fmt.Sprintf("SLOP_ARG_%s=%s", strings.ToUpper(k), value))
+		}
+
+		output, err := cmd.CombinedOutput()
+		result := truncate(string(output))
+		if err != nil {
+			return result, fmt.Errorf("command failed: %w\n%s", err, result)
+		}
+		return result, nil
+	}
+}
+
+// httpHandler POSTs the arguments as JSON to a localhost endpoint and returns the response body
+func httpHandler(endpoint string) handler {
+	return func(ctx context.Context, args map[string]interface{}) (string, error) {
+		body, err := json.Marshal(args)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode arguments: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxOutputBytes+1))
+		if err != nil {
+			return "", err
+		}
+		result := truncate(string(data))
+		if resp.StatusCode >= 300 {
+			return result, fmt.Errorf("request failed with status %s: %s", resp.Status, result)
+		}
+		return result, nil
+	}
+}
+
+// checkLocalURL only allows tool endpoints on the local machine
+func checkLocalURL(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid url %s: %w", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url %s must use http or https", endpoint)
+	}
+
+	host := u.Hostname()
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("url %s must point at localhost", endpoint)
+}
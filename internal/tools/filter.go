@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"path"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/config"
+)
+
+// Filter returns the tools selected by a list of glob patterns such as
+// [filesystem__*, !filesystem__write_file]. A tool is selected if it matches
+// any include pattern (or there are none) and no ! exclude pattern.
+func Filter(tools map[string]config.Tool, patterns []string) map[string]config.Tool {
+	if len(patterns) == 0 {
+		return tools
+	}
+
+	result := make(map[string]config.Tool)
+	for name, tool := range tools {
+		if IsActive(name, patterns) {
+			result[name] = tool
+		}
+	}
+	return result
+}
+
+// IsActive reports whether a tool name is selected by the patterns
+func IsActive(name string, patterns []string) bool {
+	hasIncludes := false
+	included := false
+
+	for _, pattern := range patterns {
+		if exclude, ok := strings.CutPrefix(pattern, "!"); ok {
+			if matched, _ := path.Match(exclude, name); matched {
+				return false
+			}
+			continue
+		}
+		hasIncludes = true
+		if matched, _ := path.Match(pattern, name); matched {
+			included = true
+		}
+	}
+
+	return included || !hasIncludes
+}
@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/isaacphi/slop/internal/config"
+)
+
+// handler executes a tool with its parsed arguments and returns the text result
+type handler func(ctx context.Context, args map[string]interface{}) (string, error)
+
+// Registry holds tools implemented by slop itself rather than by an MCP server:
+// built-ins such as read_file, and tools declared in model config that are
+// backed by a shell command or a localhost HTTP endpoint
+type Registry struct {
+	tools    map[string]config.Tool
+	handlers map[string]handler
+	roots    []string
+	commands []string
+}
+
+// New creates a registry with the enabled built-ins and the executable
+// tools from the model's config
+func New(cfg config.NativeTools, modelTools map[string]config.Tool) (*Registry, error) {
+	r := &Registry{
+		tools:    make(map[string]config.Tool),
+		handlers: make(map[string]handler),
+		commands: cfg.AllowedCommands,
+	}
+
+	for _, root := range cfg.Roots {
+		abs, err := filepath.Abs(config.ExpandHome(root))
+		if err != nil {
+			return nil, fmt.Errorf("invalid tool root %s: %w", root, err)
+		}
+		if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = resolved
+		}
+		r.roots = append(r.roots, abs)
+	}
+
+	for _, name := range cfg.Enabled {
+		builtin, ok := builtins[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown built-in tool %s", name)
+		}
+		if name == "run_command" && len(r.commands) == 0 {
+			slog.Debug("run_command enabled without allowedCommands, skipping")
+			continue
+		}
+		if name != "run_command" && len(r.roots) == 0 {
+			slog.Debug("file tool enabled without roots, skipping", "tool", name)
+			continue
+		}
+		tool := builtin.tool
+		tool.Name = name
+		r.tools[name] = tool
+		r.handlers[name] = builtin.handler(r)
+	}
+
+	for name, tool := range modelTools {
+		var h handler
+		switch {
+		case tool.Command != "" && tool.URL != "":
+			return nil, fmt.Errorf("tool %s sets both command and url", name)
+		case tool.Command != "":
+			h = commandHandler(tool.Command)
+		case tool.URL != "":
+			if err := checkLocalURL(tool.URL); err != nil {
+				return nil, fmt.Errorf("tool %s: %w", name, err)
+			}
+			h = httpHandler(tool.URL)
+		default:
+			slog.Debug("tool has no executor, skipping", "tool", name)
+			continue
+		}
+		tool.Name = name
+		r.tools[name] = tool
+		r.handlers[name] = h
+	}
+
+	return r, nil
+}
+
+// GetTools returns a map of all available tools
+func (r *Registry) GetTools() map[string]config.Tool {
+	tools := make(map[string]config.Tool, len(r.tools))
+	for k, v := range r.tools {
+		tools[k] = v
+	}
+	return tools
+}
+
+// Has reports whether the registry provides the named tool
+func (r *Registry) Has(name string) bool {
+	_, ok := r.handlers[name]
+	return ok
+}
+
+// CallTool executes a tool with arguments in JSON form
+func (r *Registry) CallTool(ctx context.Context, name string, arguments json.RawMessage) (string, error) {
+	h, ok := r.handlers[name]
+	if !ok {
+		return "", fmt.Errorf("tool %s not found", name)
+	}
+
+	args := make(map[string]interface{})
+	if len(arguments) > 0 {
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+
+	return h(ctx, args)
+}
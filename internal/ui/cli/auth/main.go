@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/isaacphi/slop/internal/app"
+	"github.com/isaacphi/slop/internal/credentials"
+	"github.com/isaacphi/slop/internal/llm"
+	"github.com/isaacphi/slop/internal/ui/output"
+	"github.com/spf13/cobra"
+)
+
+var usePassphrase bool
+
+var AuthCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage stored API keys",
+	Long: `Store provider API keys encrypted in the global config directory. A stored key
+is used for models of its provider that do not set apiKey in config, in place
+of the provider's environment variable such as OPENAI_API_KEY.
+
+Keys are encrypted with a key file created next to them, or with a passphrase
+if the first key is stored with --passphrase. The passphrase is read from
+$SLOP_PASSPHRASE, or prompted for by these commands.`,
+}
+
+var setCmd = &cobra.Command{
+	Use:   "set [provider]",
+	Short: "Store the API key for a provider",
+	Long:  "Store the API key for a provider. The key is prompted for, or read from stdin if it is not a terminal.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		provider := args[0]
+		if !slices.Contains(llm.Providers, provider) {
+			return fmt.Errorf("unknown provider %q, expected one of %s", provider, strings.Join(llm.Providers, ", "))
+		}
+
+		key, err := readKey(provider)
+		if err != nil {
+			return err
+		}
+		if key == "" {
+			return fmt.Errorf("no key given")
+		}
+
+		store, err := newStore()
+		if err != nil {
+			return err
+		}
+		if err := store.Set(provider, key); err != nil {
+			return err
+		}
+		fmt.Printf("Stored %s key in %s\n", provider, store.Path())
+		return nil
+	},
+}
+
+var listCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List stored API keys",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := newStore()
+		if err != nil {
+			return err
+		}
+		entries, err := store.List()
+		if err != nil {
+			return err
+		}
+
+		if output.IsStructured() {
+			return output.Render(os.Stdout, entries)
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("No stored keys")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Provider\tUpdated")
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t%s\n", e.Provider, e.Updated.Format("2006-01-02 15:04"))
+		}
+		return w.Flush()
+	},
+}
+
+var removeCmd = &cobra.Command{
+	Use:   "rm [provider]",
+	Short: "Delete the stored API key for a provider",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := newStore()
+		if err != nil {
+			return err
+		}
+		if err := store.Remove(args[0]); err != nil {
+			if errors.Is(err, credentials.ErrNotFound) {
+				return fmt.Errorf("no key stored for %s", args[0])
+			}
+			return err
+		}
+		fmt.Printf("Removed %s key\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	setCmd.Flags().BoolVar(&usePassphrase, "passphrase", false, "Encrypt keys with a passphrase instead of a key file, when storing the first key")
+
+	// Keys are stored outside the project config, which need not be valid
+	for _, cmd := range []*cobra.Command{setCmd, listCmd, removeCmd} {
+		cmd.Annotations = map[string]string{app.SkipInit: ""}
+	}
+	AuthCmd.AddCommand(setCmd, listCmd, removeCmd)
+}
+
+// newStore returns the default store, prompting for the passphrase when it
+// is not in the environment
+func newStore() (*credentials.Store, error) {
+	store, err := credentials.Default()
+	if err != nil {
+		return nil, err
+	}
+	store.Passphrase = func(create bool) (string, error) {
+		if passphrase := os.Getenv(credentials.PassphraseEnv); passphrase != "" {
+			return passphrase, nil
+		}
+		if create && !usePassphrase {
+			return "", nil
+		}
+		passphrase, err := promptSecret("Passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if create {
+			confirm, err := promptSecret("Repeat passphrase: ")
+			if err != nil {
+				return "", err
+			}
+			if confirm != passphrase {
+				return "", fmt.Errorf("passphrases do not match")
+			}
+		}
+		return passphrase, nil
+	}
+	return store, nil
+}
+
+// readKey reads a key from stdin if it is piped, or prompts for it
+func readKey(provider string) (string, error) {
+	stat, err := os.Stdin.Stat()
+	if err == nil && stat.Mode()&os.ModeCharDevice == 0 {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("failed to read key from stdin: %w", err)
+		}
+		return strings.TrimSpace(line), nil
+	}
+	return promptSecret(fmt.Sprintf("%s API key: ", provider))
+}
+
+// promptSecret reads a line from the terminal without echoing it
+func promptSecret(prompt string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("no terminal to prompt on, set %s", credentials.PassphraseEnv)
+	}
+	defer tty.Close()
+
+	stty := func(arg string) error {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = tty
+		return cmd.Run()
+	}
+	if err := stty("-echo"); err != nil {
+		return "", fmt.Errorf("failed to disable terminal echo: %w", err)
+	}
+	defer stty("echo")
+
+	fmt.Fprint(tty, prompt)
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	fmt.Fprintln(tty)
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
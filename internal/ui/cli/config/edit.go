@@ -0,0 +1,99 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/app"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/ui/editor"
+	"github.com/spf13/cobra"
+)
+
+var editCmd = &cobra.Command{
+	Use:   "edit [name]",
+	Short: "Edit a config file in $EDITOR",
+	Long: `Open a config file in $EDITOR and validate the configuration when it is saved.
+Name selects name.slop.yaml in the local .slop directory, or with --global the
+global config directory. It defaults to config.slop.yaml, or the only config
+file in the directory.`,
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: map[string]string{app.SkipInit: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := editFile(args)
+		if err != nil {
+			return err
+		}
+
+		original, err := os.ReadFile(file)
+		existed := err == nil
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+
+		stat, _ := os.Stdin.Stat()
+		interactive := stat != nil && stat.Mode()&os.ModeCharDevice != 0
+		reader := bufio.NewReader(os.Stdin)
+
+		for {
+			if err := editor.Open(file); err != nil {
+				return err
+			}
+			validateErr := config.Validate(nil)
+			if validateErr == nil {
+				fmt.Printf("Saved %s\n", file)
+				return nil
+			}
+
+			fmt.Fprintln(os.Stderr, validateErr)
+			if !interactive {
+				return errors.New("configuration is invalid")
+			}
+			fmt.Print("(e)dit again, (r)evert or (k)eep anyway? [e] ")
+			answer, _ := reader.ReadString('\n')
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "r", "revert":
+				if !existed {
+					return os.Remove(file)
+				}
+				return os.WriteFile(file, original, 0o644)
+			case "k", "keep":
+				return nil
+			}
+		}
+	},
+}
+
+// editFile returns the config file that edit opens
+func editFile(args []string) (string, error) {
+	dir, err := scope().Dir()
+	if err != nil {
+		return "", err
+	}
+	if len(args) > 0 {
+		name := args[0]
+		if !strings.HasSuffix(name, ".slop.yaml") && !strings.HasSuffix(name, ".slop.json") {
+			name += ".slop.yaml"
+		}
+		return filepath.Join(dir, name), nil
+	}
+
+	defaultFile := filepath.Join(dir, config.DefaultFile)
+	if _, err := os.Stat(defaultFile); err == nil {
+		return defaultFile, nil
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*slop.yaml"))
+	jsonMatches, _ := filepath.Glob(filepath.Join(dir, "*slop.json"))
+	matches = append(matches, jsonMatches...)
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	return defaultFile, nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/isaacphi/slop/internal/app"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/ui/output"
+	"github.com/spf13/cobra"
+)
+
+// explanation is the result of explain as printed by the structured output formats
+type explanation struct {
+	Key     string          `json:"key"`
+	Value   interface{}     `json:"value,omitempty"`
+	Changes []config.Change `json:"changes"`
+}
+
+var explainCmd = &cobra.Command{
+	Use:   "explain [key]",
+	Short: "Show how each config layer contributed to a value",
+	Long: `List the changes each layer made to a key, in the order they were applied:
+defaults, global and local config files, the profile, environment variables
+and --set. Lists show the elements each layer added.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := app.Get().Config
+		key := args[0]
+
+		result := explanation{Key: key, Changes: cfg.Explain(key)}
+		value, set := cfg.Get(key)
+		if set {
+			result.Value = value
+		}
+		if len(result.Changes) == 0 && !set {
+			return fmt.Errorf("%s is not set by any config layer", key)
+		}
+
+		if output.IsStructured() {
+			return output.Render(os.Stdout, result)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SOURCE\tACTION\tKEY\tVALUE")
+		for _, change := range result.Changes {
+			v := ""
+			if change.Value != nil {
+				v = fmt.Sprint(change.Value)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", change.Source, change.Action, change.Key, v)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if set {
+			fmt.Printf("\nResult: %v\n", value)
+		} else {
+			fmt.Println("\nResult: not set")
+		}
+		return nil
+	},
+}
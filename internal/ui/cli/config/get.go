@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/isaacphi/slop/internal/app"
+	"github.com/isaacphi/slop/internal/ui/output"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var getCmd = &cobra.Command{
+	Use:   "get [key]",
+	Short: "Print a configuration value",
+	Long:  "Print the value of a key in the merged configuration, e.g. slop config get agent.maxSteps",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := app.Get().Config
+
+		value, ok := cfg.Get(args[0])
+		if !ok {
+			return fmt.Errorf("%s is not set", args[0])
+		}
+
+		if output.IsStructured() {
+			return output.Render(os.Stdout, value)
+		}
+
+		switch value.(type) {
+		case map[string]interface{}, []interface{}, []string:
+			data, err := yaml.Marshal(value)
+			if err != nil {
+				return err
+			}
+			fmt.Print(string(data))
+		default:
+			fmt.Println(value)
+		}
+		return nil
+	},
+}
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/isaacphi/slop/internal/app"
+	"github.com/isaacphi/slop/internal/ui/output"
 	"github.com/spf13/cobra"
 )
 
@@ -21,6 +25,13 @@ var (
 				prefixFilter = args[0]
 			}
 
+			if output.IsStructured() {
+				for _, w := range cfg.Warnings() {
+					fmt.Fprintln(os.Stderr, w)
+				}
+				return output.Render(os.Stdout, cfg.ConfigMap(includeSources, prefixFilter))
+			}
+
 			cfg.PrintConfig(includeSources, prefixFilter)
 
 			return nil
@@ -30,4 +41,9 @@ var (
 
 func init() {
 	ConfigCmd.Flags().BoolVarP(&includeSources, "include-sources", "s", false, "Show source file for each configuration value")
+
+	for _, cmd := range []*cobra.Command{setCmd, unsetCmd, editCmd} {
+		addScopeFlags(cmd)
+	}
+	ConfigCmd.AddCommand(getCmd, setCmd, unsetCmd, editCmd, validateCmd, explainCmd, schemaCmd)
 }
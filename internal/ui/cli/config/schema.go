@@ -0,0 +1,68 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/isaacphi/slop/internal/app"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/ui/output"
+	"github.com/spf13/cobra"
+)
+
+// schemaFile is the name of the file written by schema --write
+const schemaFile = "slop.schema.json"
+
+var writeSchema bool
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for config files",
+	Long: `Print a JSON Schema describing config files, for validation and
+autocompletion in editors. With --write it is saved to the global config
+directory. To use it with the YAML language server, e.g. in VS Code or Neovim,
+add this comment to the top of a config file:
+
+  # yaml-language-server: $schema=<path to slop.schema.json>`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{app.SkipInit: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		schema := config.JSONSchema()
+
+		if writeSchema {
+			dir, err := config.GlobalDir()
+			if err != nil {
+				return err
+			}
+			data, err := json.MarshalIndent(schema, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+			path := filepath.Join(dir, schemaFile)
+			if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+				return err
+			}
+			fmt.Printf("Wrote %s\nAdd this comment to the top of config files to use it:\n\n  # yaml-language-server: $schema=%s\n", path, path)
+			return nil
+		}
+
+		if output.IsStructured() {
+			return output.Render(os.Stdout, schema)
+		}
+		data, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	},
+}
+
+func init() {
+	schemaCmd.Flags().BoolVar(&writeSchema, "write", false, "Save the schema to "+schemaFile+" in the global config directory")
+}
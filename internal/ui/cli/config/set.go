@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/isaacphi/slop/internal/app"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	globalFlag bool
+	localFlag  bool
+)
+
+var setCmd = &cobra.Command{
+	Use:   "set [key] [value]",
+	Short: "Set a configuration value",
+	Long: `Set a key in a config file, e.g. slop config set agent.maxSteps 50
+
+The value is parsed as YAML, so 50 is a number and [a, b] is a list. The key is
+written to the last file in the local .slop directory that sets it, or to
+config.slop.yaml. Use --global to write to the global config directory instead.
+Comments and formatting in the file are kept, and nothing is written if the
+configuration would be invalid.`,
+	Args:        cobra.ExactArgs(2),
+	Annotations: map[string]string{app.SkipInit: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := config.TargetFile(scope(), args[0])
+		if err != nil {
+			return err
+		}
+		if err := config.SetValue(file, args[0], args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Set %s in %s\n", args[0], file)
+		return nil
+	},
+}
+
+var unsetCmd = &cobra.Command{
+	Use:         "unset [key]",
+	Short:       "Remove a configuration value",
+	Long:        "Remove a key from the config file that sets it, in the local .slop directory or with --global the global config directory.",
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{app.SkipInit: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := config.TargetFile(scope(), args[0])
+		if err != nil {
+			return err
+		}
+		if err := config.UnsetValue(file, args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s from %s\n", args[0], file)
+		return nil
+	},
+}
+
+func scope() config.Scope {
+	if globalFlag {
+		return config.ScopeGlobal
+	}
+	return config.ScopeLocal
+}
+
+// addScopeFlags adds --global and --local to a command that writes config files
+func addScopeFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&globalFlag, "global", false, "Use the global config directory")
+	cmd.Flags().BoolVar(&localFlag, "local", false, "Use the local .slop directory (default)")
+	cmd.MarkFlagsMutuallyExclusive("global", "local")
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/isaacphi/slop/internal/app"
+	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/ui/output"
+	"github.com/spf13/cobra"
+)
+
+// validation is the result of validate as printed by the structured output formats
+type validation struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Check that the configuration is valid",
+	Long: `Load and validate the configuration. If a file is given, it is validated as
+part of the configuration, replacing the file of the same path if there is one.`,
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: map[string]string{app.SkipInit: ""},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		replacements := make(map[string][]byte)
+		if len(args) > 0 {
+			content, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			replacements[args[0]] = content
+		}
+
+		err := config.Validate(replacements)
+		if output.IsStructured() {
+			result := validation{Valid: err == nil}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			if renderErr := output.Render(os.Stdout, result); renderErr != nil {
+				return renderErr
+			}
+			if err != nil {
+				cmd.SilenceErrors = true
+				cmd.SilenceUsage = true
+			}
+			return err
+		}
+		if err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		fmt.Println("Configuration is valid")
+		return nil
+	},
+}
@@ -0,0 +1,144 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/isaacphi/slop/internal/app"
+	"github.com/isaacphi/slop/internal/daemon"
+	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/ui/output"
+	"github.com/spf13/cobra"
+)
+
+var DaemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Manage the background MCP server daemon",
+	Long: `The daemon keeps MCP servers running between slop invocations.
+While it is running, commands use its servers instead of starting their own.`,
+}
+
+var startCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the daemon in the background",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Forward global flags so the daemon loads the same configuration
+		var forwarded []string
+		for _, name := range []string{"log-level", "log-file", "profile", "config-dir"} {
+			if flag := cmd.Flags().Lookup(name); flag != nil && flag.Changed {
+				forwarded = append(forwarded, "--"+name, flag.Value.String())
+			}
+		}
+		sets, _ := cmd.Flags().GetStringArray("set")
+		for _, set := range sets {
+			forwarded = append(forwarded, "--set", set)
+		}
+
+		pid, err := daemon.Start(forwarded)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Daemon started (pid %d)\n", pid)
+		return nil
+	},
+}
+
+var stopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the daemon and its MCP servers",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := daemon.Stop(cmd.Context()); err != nil {
+			if errors.Is(err, daemon.ErrNotRunning) {
+				fmt.Println("Daemon is not running")
+				return nil
+			}
+			return err
+		}
+		fmt.Println("Daemon stopped")
+		return nil
+	},
+}
+
+// daemonStatus is the daemon status as printed by the structured output formats
+type daemonStatus struct {
+	Running       bool               `json:"running"`
+	PID           int                `json:"pid,omitempty"`
+	Socket        string             `json:"socket,omitempty"`
+	StartedAt     *time.Time         `json:"startedAt,omitempty"`
+	Tools         int                `json:"tools"`
+	Servers       []mcp.ServerStatus `json:"servers,omitempty"`
+	MatchesConfig bool               `json:"matchesConfig"`
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show daemon status",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := daemon.Dial()
+		if err != nil {
+			if output.IsStructured() {
+				return output.Render(os.Stdout, daemonStatus{Running: false})
+			}
+			fmt.Println("Daemon is not running")
+			return nil
+		}
+		defer client.Shutdown()
+
+		status, err := client.DaemonStatus(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to get daemon status: %w", err)
+		}
+
+		cfg := app.Get().Config
+		matches := status.Fingerprint == daemon.Fingerprint(cfg.MCPServers)
+
+		if output.IsStructured() {
+			return output.Render(os.Stdout, daemonStatus{
+				Running:       true,
+				PID:           status.PID,
+				Socket:        daemon.SocketPath(),
+				StartedAt:     &status.StartedAt,
+				Tools:         status.ToolCount,
+				Servers:       client.Status(),
+				MatchesConfig: matches,
+			})
+		}
+
+		fmt.Printf("Daemon is running (pid %d)\n", status.PID)
+		fmt.Printf("Socket: %s\n", daemon.SocketPath())
+		fmt.Printf("Started: %s (up %s)\n", status.StartedAt.Format(time.RFC822), time.Since(status.StartedAt).Round(time.Second))
+		fmt.Printf("Tools: %d\n", status.ToolCount)
+		fmt.Println("Servers:")
+		for _, server := range client.Status() {
+			fmt.Printf("  %s: %s (restarts: %d)\n", server.Name, server.State, server.Restarts)
+			if server.Error != "" {
+				fmt.Printf("    error: %s\n", server.Error)
+			}
+		}
+		if !matches {
+			fmt.Println("Note: the daemon's MCP servers differ from the current configuration or were started from another directory, so it will not be used here")
+		}
+		return nil
+	},
+}
+
+var runCmd = &cobra.Command{
+	Use:    "run",
+	Short:  "Run the daemon in the foreground",
+	Hidden: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		cfg := app.Get().Config
+		return daemon.Run(ctx, cfg.MCPServers)
+	},
+}
+
+func init() {
+	DaemonCmd.AddCommand(startCmd, stopCmd, statusCmd, runCmd)
+}
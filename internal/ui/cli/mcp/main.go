@@ -1,100 +1,107 @@
 package mcp
 
 import (
-	"context"
 	"fmt"
+	"os"
 	"sort"
+	"strings"
 
 	"github.com/isaacphi/slop/internal/app"
 	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/daemon"
 	"github.com/isaacphi/slop/internal/mcp"
+	toolfilter "github.com/isaacphi/slop/internal/tools"
+	"github.com/isaacphi/slop/internal/ui/output"
 	"github.com/spf13/cobra"
 )
 
 var (
+	modelFlag string
+
 	MCPCmd = &cobra.Command{
 		Use:   "mcp",
 		Short: "Display MCP tools information",
-		Long:  "Initialize MCP servers and display information about available tools",
+		Long:  "Initialize MCP servers and display information about the tools offered to the model, including those run by slop itself",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Load configuration
 			cfg := app.Get().Config
 
-			// Create and initialize MCP client
-			client := mcp.New(cfg.MCPServers)
-			if err := client.Initialize(context.Background()); err != nil {
-				return fmt.Errorf("failed to initialize MCP client: %w", err)
+			// Connect to the daemon or start servers in-process
+			client, err := daemon.Connect(cmd.Context(), cfg.MCPServers)
+			if err != nil {
+				return err
 			}
 			defer client.Shutdown()
 
-			// Get all available tools
-			tools := client.GetTools()
+			// Tools offered to the model are narrowed by its activeTools globs
+			modelName := cfg.MsgModel()
+			if modelFlag != "" {
+				modelName = modelFlag
+			}
+			modelName, model, err := cfg.ResolveModel(modelName)
+			if err != nil {
+				return err
+			}
 
-			// Create sorted list of tool names for consistent output
-			var toolNames []string
-			for name := range tools {
-				toolNames = append(toolNames, name)
+			// Native and config-declared tools, which take precedence over MCP tools of the same name
+			registry, err := toolfilter.New(cfg.NativeTools, model.Tools)
+			if err != nil {
+				return fmt.Errorf("failed to initialize native tools: %w", err)
 			}
-			sort.Strings(toolNames)
+			nativeTools := registry.GetTools()
 
 			// Group tools by server
 			serverTools := make(map[string]map[string]config.Tool)
-			for name, tool := range tools {
-				serverName := tool.Name
+			for name, tool := range client.GetTools() {
+				if _, ok := nativeTools[name]; ok {
+					continue
+				}
+				serverName, _, _ := strings.Cut(name, "__")
 				if serverTools[serverName] == nil {
 					serverTools[serverName] = make(map[string]config.Tool)
 				}
 				serverTools[serverName][name] = tool
 			}
 
-			// Get sorted list of server names
-			var serverNames []string
-			for server := range serverTools {
-				serverNames = append(serverNames, server)
+			statuses := client.Status()
+			if len(nativeTools) > 0 {
+				serverTools[nativeGroup] = nativeTools
+				statuses = append(statuses, mcp.ServerStatus{Name: nativeGroup, State: mcp.StateReady, Tools: len(nativeTools)})
 			}
-			sort.Strings(serverNames)
 
-			// Print each server's tools
-			for _, serverName := range serverNames {
-				fmt.Printf("%s:\n", serverName)
+			if output.IsStructured() {
+				servers := make([]serverItem, 0)
+				for _, status := range statuses {
+					server := serverItem{ServerStatus: status, Tools: make([]toolItem, 0)}
+					for _, name := range sortedNames(serverTools[status.Name]) {
+						tool := serverTools[status.Name][name]
+						server.Tools = append(server.Tools, toolItem{
+							Name:        name,
+							Description: tool.Description,
+							Active:      toolfilter.IsActive(name, model.ActiveTools),
+							InputSchema: tool.JSONSchema(),
+						})
+					}
+					servers = append(servers, server)
+				}
+				return output.Render(os.Stdout, servers)
+			}
 
-				// Get sorted tool names for this server
-				var serverToolNames []string
-				for name := range serverTools[serverName] {
-					serverToolNames = append(serverToolNames, name)
+			// Print each server's status and tools
+			for _, status := range statuses {
+				serverName := status.Name
+				fmt.Printf("%s:\n", serverName)
+				fmt.Printf("  status: %s\n", status.State)
+				if status.Restarts > 0 {
+					fmt.Printf("  restarts: %d\n", status.Restarts)
+				}
+				if status.Error != "" {
+					fmt.Printf("  error: %s\n", status.Error)
 				}
-				sort.Strings(serverToolNames)
 
 				// Print each tool's information
-				for _, name := range serverToolNames {
-					tool := serverTools[serverName][name]
-					fmt.Printf("  %s:\n", name)
-					fmt.Printf("    description: %s\n", tool.Description)
-					fmt.Printf("    parameters:\n")
-
-					// Get sorted parameter names
-					var paramNames []string
-					for paramName := range tool.Parameters.Properties {
-						paramNames = append(paramNames, paramName)
-					}
-					sort.Strings(paramNames)
-
-					// Print each parameter's information
-					for _, paramName := range paramNames {
-						prop := tool.Parameters.Properties[paramName]
-						fmt.Printf("      %s:\n", paramName)
-						fmt.Printf("        type: %s\n", prop.Type)
-						if prop.Description != "" {
-							fmt.Printf("        description: %s\n", prop.Description)
-						}
-						// Check if parameter is required
-						for _, req := range tool.Parameters.Required {
-							if req == paramName {
-								fmt.Printf("        required: true\n")
-								break
-							}
-						}
-					}
+				for _, name := range sortedNames(serverTools[serverName]) {
+					printTool(name, serverTools[serverName][name], model.ActiveTools)
 				}
 			}
 
@@ -102,3 +109,110 @@ var (
 		},
 	}
 )
+
+// nativeGroup lists the tools run by slop itself, as in the tool execution log
+const nativeGroup = "native"
+
+// printTool prints a tool and its parameters
+func printTool(name string, tool config.Tool, activeTools []string) {
+	fmt.Printf("  %s:\n", name)
+	fmt.Printf("    description: %s\n", tool.Description)
+	fmt.Printf("    active: %t\n", toolfilter.IsActive(name, activeTools))
+	fmt.Printf("    parameters:\n")
+
+	schema := tool.JSONSchema()
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := make(map[string]bool)
+	for _, req := range stringList(schema["required"]) {
+		required[req] = true
+	}
+
+	// Get sorted parameter names
+	var paramNames []string
+	for paramName := range properties {
+		paramNames = append(paramNames, paramName)
+	}
+	sort.Strings(paramNames)
+
+	// Print each parameter's information
+	for _, paramName := range paramNames {
+		prop, _ := properties[paramName].(map[string]interface{})
+		fmt.Printf("      %s:\n", paramName)
+		fmt.Printf("        type: %s\n", schemaType(prop))
+		if description, ok := prop["description"].(string); ok && description != "" {
+			fmt.Printf("        description: %s\n", description)
+		}
+		if required[paramName] {
+			fmt.Printf("        required: true\n")
+		}
+	}
+}
+
+// serverItem is a server and its tools as printed by the structured output formats
+type serverItem struct {
+	mcp.ServerStatus
+	Tools []toolItem `json:"tools"`
+}
+
+type toolItem struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Active      bool                   `json:"active"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+func sortedNames(tools map[string]config.Tool) []string {
+	var names []string
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	MCPCmd.Flags().StringVarP(&modelFlag, "model", "m", "", "Show which tools are active for this model")
+	MCPCmd.AddCommand(resourcesCmd, promptsCmd)
+}
+
+// schemaType summarizes the type of a JSON Schema property, e.g. "string | null"
+func schemaType(schema map[string]interface{}) string {
+	if types := stringList(schema["type"]); len(types) > 0 {
+		return strings.Join(types, " | ")
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		return ref
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		branches, ok := schema[key].([]interface{})
+		if !ok {
+			continue
+		}
+		var types []string
+		for _, branch := range branches {
+			if branchMap, ok := branch.(map[string]interface{}); ok {
+				types = append(types, schemaType(branchMap))
+			}
+		}
+		return strings.Join(types, " | ")
+	}
+	return "any"
+}
+
+func stringList(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	case []interface{}:
+		var list []string
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
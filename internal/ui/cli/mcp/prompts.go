@@ -0,0 +1,60 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/isaacphi/slop/internal/app"
+	"github.com/isaacphi/slop/internal/daemon"
+	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/ui/output"
+	"github.com/spf13/cobra"
+)
+
+var promptsCmd = &cobra.Command{
+	Use:   "prompts",
+	Short: "List MCP prompts",
+	Long:  "List prompts from all MCP servers. Expand one into a message with slop msg send --prompt server:name key=value",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := app.Get().Config
+
+		client, err := daemon.Connect(cmd.Context(), cfg.MCPServers)
+		if err != nil {
+			return err
+		}
+		defer client.Shutdown()
+
+		prompts, err := client.ListPrompts(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		if output.IsStructured() {
+			if prompts == nil {
+				prompts = []mcp.Prompt{}
+			}
+			return output.Render(os.Stdout, prompts)
+		}
+
+		for _, p := range prompts {
+			fmt.Printf("%s:%s\n", p.Server, p.Name)
+			if p.Description != "" {
+				fmt.Printf("  description: %s\n", p.Description)
+			}
+			if len(p.Arguments) > 0 {
+				fmt.Printf("  arguments:\n")
+				for _, arg := range p.Arguments {
+					fmt.Printf("    %s:\n", arg.Name)
+					if arg.Description != "" {
+						fmt.Printf("      description: %s\n", arg.Description)
+					}
+					if arg.Required {
+						fmt.Printf("      required: true\n")
+					}
+				}
+			}
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/isaacphi/slop/internal/app"
+	"github.com/isaacphi/slop/internal/daemon"
+	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/ui/output"
+	"github.com/spf13/cobra"
+)
+
+var resourcesCmd = &cobra.Command{
+	Use:   "resources [uri]",
+	Short: "List MCP resources, or print the contents of one",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := app.Get().Config
+
+		client, err := daemon.Connect(cmd.Context(), cfg.MCPServers)
+		if err != nil {
+			return err
+		}
+		defer client.Shutdown()
+
+		if len(args) > 0 {
+			contents, err := client.ReadResource(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Println(contents)
+			return nil
+		}
+
+		resources, err := client.ListResources(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		if output.IsStructured() {
+			if resources == nil {
+				resources = []mcp.Resource{}
+			}
+			return output.Render(os.Stdout, resources)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Server\tURI\tName\tDescription")
+		for _, r := range resources {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Server, r.URI, r.Name, r.Description)
+		}
+		w.Flush()
+
+		return nil
+	},
+}
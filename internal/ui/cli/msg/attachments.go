@@ -0,0 +1,69 @@
+package msg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/isaacphi/slop/internal/mcp"
+)
+
+var (
+	resourceFlags []string
+	promptFlags   []string
+)
+
+// expandAttachments prepends any requested MCP resources and prompts to the message content
+func expandAttachments(ctx context.Context, client mcp.Service, content string) (string, error) {
+	var parts []string
+
+	for _, uri := range resourceFlags {
+		contents, err := client.ReadResource(ctx, uri)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, fmt.Sprintf("<resource uri=%q>\n%s\n</resource>", uri, contents))
+	}
+
+	for _, p := range promptFlags {
+		serverName, promptName, arguments, err := parsePromptFlag(p)
+		if err != nil {
+			return "", err
+		}
+		text, err := client.GetPrompt(ctx, serverName, promptName, arguments)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, text)
+	}
+
+	if content != "" {
+		parts = append(parts, content)
+	}
+
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// parsePromptFlag parses a prompt reference of the form "server:name key=value ..."
+func parsePromptFlag(flag string) (string, string, map[string]string, error) {
+	fields := strings.Fields(flag)
+	if len(fields) == 0 {
+		return "", "", nil, fmt.Errorf("empty prompt reference")
+	}
+
+	serverName, promptName, ok := strings.Cut(fields[0], ":")
+	if !ok || serverName == "" || promptName == "" {
+		return "", "", nil, fmt.Errorf("invalid prompt reference %q, expected 'server:name'", fields[0])
+	}
+
+	arguments := make(map[string]string)
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return "", "", nil, fmt.Errorf("invalid prompt argument %q, expected 'key=value'", field)
+		}
+		arguments[key] = value
+	}
+
+	return serverName, promptName, arguments, nil
+}
@@ -0,0 +1,48 @@
+package msg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/message"
+)
+
+// contextMessages is how many recent messages are shown when composing in the editor
+const contextMessages = 6
+
+// recentContext returns the last messages of a thread for display while
+// composing, ending at upTo if it is set
+func recentContext(ctx context.Context, service *message.MessageService, threadID uuid.UUID, upTo *uuid.UUID) (string, error) {
+	messages, err := service.GetThreadMessages(ctx, threadID, upTo)
+	if err != nil {
+		return "", fmt.Errorf("failed to get thread messages: %w", err)
+	}
+	if upTo != nil {
+		for i, msg := range messages {
+			if msg.ID == *upTo {
+				messages = messages[:i+1]
+				break
+			}
+		}
+	}
+	if len(messages) == 0 {
+		return "", nil
+	}
+	if len(messages) > contextMessages {
+		messages = messages[len(messages)-contextMessages:]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Thread %s\n", threadID.String()[:8])
+	for _, msg := range messages {
+		role := "You"
+		if msg.Role == domain.RoleAssistant {
+			role = "Slop"
+		}
+		fmt.Fprintf(&b, "\n%s - %s:\n%s\n", msg.ID.String()[:8], role, strings.TrimSpace(msg.Content))
+	}
+	return b.String(), nil
+}
@@ -1,7 +1,6 @@
 package msg
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"os"
@@ -11,8 +10,11 @@ import (
 
 	"github.com/isaacphi/slop/internal/agent"
 	"github.com/isaacphi/slop/internal/app"
-	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/daemon"
+	"github.com/isaacphi/slop/internal/hooks"
 	"github.com/isaacphi/slop/internal/message"
+	"github.com/isaacphi/slop/internal/tools"
+	"github.com/isaacphi/slop/internal/ui/editor"
 	"github.com/spf13/cobra"
 )
 
@@ -44,8 +46,16 @@ Both threadID and messageID can be partial IDs - they will match the first threa
 		if err != nil {
 			return err
 		}
-		mcpClient := mcp.New(cfg.MCPServers)
-		agentService := agent.New(service, mcpClient, cfg.Agent)
+		mcpClient, err := daemon.Connect(ctx, cfg.MCPServers)
+		if err != nil {
+			return err
+		}
+		defer mcpClient.Shutdown()
+		toolRegistry, err := tools.New(cfg.NativeTools, service.GetModelConfig().Tools)
+		if err != nil {
+			return fmt.Errorf("failed to initialize native tools: %w", err)
+		}
+		agentService := agent.New(service, mcpClient, toolRegistry, hooks.New(cfg.Hooks), cfg.Agent)
 
 		// Find thread by partial ID
 		thread, err := service.FindThreadByPartialID(ctx, args[0])
@@ -75,6 +85,24 @@ Both threadID and messageID can be partial IDs - they will match the first threa
 			}
 		}
 
+		// Compose in the editor, starting from the message being edited
+		if editorFlag {
+			if initialMessage == "" {
+				initialMessage = targetMessage.Content
+			}
+			var threadContext string
+			if targetMessage.ParentID != nil {
+				threadContext, err = recentContext(ctx, service, thread.ID, targetMessage.ParentID)
+				if err != nil {
+					return err
+				}
+			}
+			initialMessage, err = editor.Compose(initialMessage, threadContext)
+			if err != nil {
+				return err
+			}
+		}
+
 		if initialMessage == "" {
 			return fmt.Errorf("no message provided")
 		}
@@ -89,32 +117,12 @@ Both threadID and messageID can be partial IDs - they will match the first threa
 		}
 
 		// In edit.go RunE function, replace the send logic with:
-		if err := sendMessage(ctx, agentService, sendOptions); err != nil {
+		if err := sendMessage(ctx, cmd, agentService.SendMessage, sendOptions); err != nil {
 			return err
 		}
 
-		// Add followup mode similar to send.go:
 		if followupFlag {
-			reader := bufio.NewReader(os.Stdin)
-			for {
-				fmt.Print("\nReply: ")
-				followupMessage, err := reader.ReadString('\n')
-				if err == io.EOF {
-					break
-				}
-				if err != nil {
-					return fmt.Errorf("failed to read input: %w", err)
-				}
-
-				followupMessage = strings.TrimSpace(followupMessage)
-				if followupMessage == "" {
-					continue
-				}
-
-				if err := sendMessage(ctx, agentService, sendOptions); err != nil {
-					return err
-				}
-			}
+			return followup(ctx, cmd, service, agentService, sendOptions)
 		}
 
 		return nil
@@ -123,6 +131,7 @@ Both threadID and messageID can be partial IDs - they will match the first threa
 
 func init() {
 	editCmd.Flags().BoolVarP(&followupFlag, "followup", "f", false, "Enable followup mode")
+	editCmd.Flags().BoolVarP(&editorFlag, "editor", "e", false, "Compose the new message in $EDITOR, starting from the message being edited")
 	editCmd.Flags().StringVarP(&modelFlag, "model", "m", "", "Specify the model to use")
 	editCmd.Flags().BoolVarP(&noStreamFlag, "no-stream", "n", false, "Disable streaming of responses")
 	editCmd.Flags().IntVar(&maxTokensFlag, "max-tokens", 0, "Override maximum length")
@@ -0,0 +1,157 @@
+package msg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/isaacphi/slop/internal/agent"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/message"
+	"github.com/isaacphi/slop/internal/ui/output"
+	"github.com/spf13/cobra"
+)
+
+// Event is a line of msg send output in ndjson format
+type Event struct {
+	Type         string `json:"type"` // text, tool_call_start, tool_call_arguments, tool_result, message_done, usage, done, stopped
+	Text         string `json:"text,omitempty"`
+	ID           string `json:"id,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Arguments    string `json:"arguments,omitempty"`
+	Result       string `json:"result,omitempty"`
+	Error        string `json:"error,omitempty"`
+	InputTokens  int    `json:"inputTokens,omitempty"`
+	OutputTokens int    `json:"outputTokens,omitempty"`
+	ThreadID     string `json:"threadId,omitempty"`
+	MessageID    string `json:"messageId,omitempty"`
+	Content      string `json:"content,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// SendResult is the output of msg send in json and yaml formats
+type SendResult struct {
+	ThreadID    string       `json:"threadId"`
+	MessageID   string       `json:"messageId"`
+	Content     string       `json:"content"`
+	ToolResults []ToolResult `json:"toolResults"`
+	Usage       Usage        `json:"usage"`
+	Stopped     string       `json:"stopped,omitempty"` // Reason the agent stopped early
+}
+
+type ToolResult struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Usage struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+}
+
+// EventStreamHandler reports a message exchange as structured data. In
+// ndjson format every event is written as it happens, otherwise a single
+// SendResult is rendered when the exchange is finished.
+type EventStreamHandler struct {
+	w      io.Writer
+	stream bool
+	mu     sync.Mutex
+	result SendResult
+}
+
+func NewEventStreamHandler(w io.Writer) *EventStreamHandler {
+	return &EventStreamHandler{
+		w:      w,
+		stream: output.Get() == output.NDJSON,
+		result: SendResult{ToolResults: []ToolResult{}},
+	}
+}
+
+func (h *EventStreamHandler) emit(e Event) error {
+	if !h.stream {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return json.NewEncoder(h.w).Encode(e)
+}
+
+func (h *EventStreamHandler) HandleTextChunk(chunk []byte) error {
+	return h.emit(Event{Type: "text", Text: string(chunk)})
+}
+
+func (h *EventStreamHandler) HandleMessageDone() error {
+	return h.emit(Event{Type: "message_done"})
+}
+
+func (h *EventStreamHandler) HandleFunctionCallStart(id, name string) error {
+	return h.emit(Event{Type: "tool_call_start", ID: id, Name: name})
+}
+
+func (h *EventStreamHandler) HandleFunctionCallChunk(chunk message.FunctionCallChunk) error {
+	return h.emit(Event{Type: "tool_call_arguments", Name: chunk.Name, Arguments: chunk.ArgumentsJson})
+}
+
+func (h *EventStreamHandler) HandleToolResult(id, name, result string, err error) error {
+	toolResult := ToolResult{ID: id, Name: name, Result: result}
+	if err != nil {
+		toolResult.Error = err.Error()
+	}
+	h.mu.Lock()
+	h.result.ToolResults = append(h.result.ToolResults, toolResult)
+	h.mu.Unlock()
+	return h.emit(Event{Type: "tool_result", ID: id, Name: name, Result: toolResult.Result, Error: toolResult.Error})
+}
+
+func (h *EventStreamHandler) HandleUsage(inputTokens, outputTokens int) error {
+	h.mu.Lock()
+	h.result.Usage.InputTokens += inputTokens
+	h.result.Usage.OutputTokens += outputTokens
+	h.mu.Unlock()
+	return h.emit(Event{Type: "usage", InputTokens: inputTokens, OutputTokens: outputTokens})
+}
+
+func (h *EventStreamHandler) Reset() {}
+
+// Finish reports the final message, or why the agent stopped
+func (h *EventStreamHandler) Finish(msg *domain.Message, stopped string) error {
+	if msg != nil {
+		h.result.ThreadID = msg.ThreadID.String()
+		h.result.MessageID = msg.ID.String()
+		h.result.Content = msg.Content
+	}
+	h.result.Stopped = stopped
+
+	if !h.stream {
+		return output.Render(h.w, h.result)
+	}
+	if stopped != "" {
+		return h.emit(Event{Type: "stopped", Reason: stopped, ThreadID: h.result.ThreadID, MessageID: h.result.MessageID})
+	}
+	return h.emit(Event{Type: "done", ThreadID: h.result.ThreadID, MessageID: h.result.MessageID, Content: h.result.Content})
+}
+
+// sendMessageStructured sends a message and reports it in the selected structured output format
+func sendMessageStructured(ctx context.Context, cmd *cobra.Command, send sendFunc, opts message.SendMessageOptions) error {
+	handler := NewEventStreamHandler(os.Stdout)
+	opts.StreamHandler = handler
+
+	resp, err := send(ctx, opts)
+	var limitErr *agent.LimitReachedError
+	if errors.As(err, &limitErr) {
+		if err := handler.Finish(resp, limitErr.Error()); err != nil {
+			return err
+		}
+		return stopped(cmd, limitErr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", pendingApproval(err))
+	}
+	return handler.Finish(resp, "")
+}
@@ -3,29 +3,51 @@ package msg
 import (
 	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
-	"github.com/google/uuid"
 	"github.com/isaacphi/slop/internal/agent"
 	"github.com/isaacphi/slop/internal/app"
-	"github.com/isaacphi/slop/internal/mcp"
+	"github.com/isaacphi/slop/internal/daemon"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/hooks"
 	"github.com/isaacphi/slop/internal/message"
+	"github.com/isaacphi/slop/internal/prompts"
+	"github.com/isaacphi/slop/internal/tools"
+	"github.com/isaacphi/slop/internal/ui/editor"
+	"github.com/isaacphi/slop/internal/ui/markdown"
+	"github.com/isaacphi/slop/internal/ui/output"
 	"github.com/spf13/cobra"
 )
 
 var (
 	continueFlag    bool
+	resumeFlag      bool
 	followupFlag    bool
 	modelFlag       string
 	threadFlag      string
 	noStreamFlag    bool
 	maxTokensFlag   int
 	temperatureFlag float64
+	toolsFlag       []string
+	noToolsFlag     bool
+	maxStepsFlag    int
+	timeoutFlag     time.Duration
+	tokenBudgetFlag int
+	costBudgetFlag  float64
+	schemaFlag      string
+	jsonFlag        bool
+	rawFlag         bool
+	templateFlag    string
+	editorFlag      bool
+	varFlags        []string
 )
 
 var sendCmd = &cobra.Command{
@@ -55,12 +77,33 @@ var sendCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		mcpClient := mcp.New(cfg.MCPServers)
-		if err := mcpClient.Initialize(context.Background()); err != nil {
-			return fmt.Errorf("failed to initialize MCP client: %w", err)
+		mcpClient, err := daemon.Connect(ctx, cfg.MCPServers)
+		if err != nil {
+			return err
 		}
 		defer mcpClient.Shutdown()
-		agentService := agent.New(service, mcpClient, cfg.Agent)
+		toolRegistry, err := tools.New(cfg.NativeTools, service.GetModelConfig().Tools)
+		if err != nil {
+			return fmt.Errorf("failed to initialize native tools: %w", err)
+		}
+		agentCfg := cfg.Agent
+		if cmd.Flags().Changed("max-steps") {
+			agentCfg.MaxSteps = maxStepsFlag
+		}
+		if cmd.Flags().Changed("timeout") {
+			agentCfg.Timeout = timeoutFlag
+		}
+		if cmd.Flags().Changed("token-budget") {
+			agentCfg.TokenBudget = tokenBudgetFlag
+		}
+		if cmd.Flags().Changed("cost-budget") {
+			agentCfg.CostBudget = costBudgetFlag
+		}
+		agentService := agent.New(service, mcpClient, toolRegistry, hooks.New(cfg.Hooks), agentCfg)
+
+		if resumeFlag {
+			return resume(ctx, cmd, args, service, agentService)
+		}
 
 		// Get the initialMessage content
 		var initialMessage string
@@ -78,91 +121,218 @@ var sendCmd = &cobra.Command{
 			}
 		}
 
-		if initialMessage == "" {
-			return fmt.Errorf("no message provided")
+		// Render the prompt template, followed by any message given
+		if templateFlag != "" {
+			vars, err := prompts.ParseVars(varFlags)
+			if err != nil {
+				return err
+			}
+			rendered, err := prompts.Render(templateFlag, vars)
+			if err != nil {
+				return err
+			}
+			if initialMessage != "" {
+				rendered += "\n\n" + initialMessage
+			}
+			initialMessage = rendered
+		} else if len(varFlags) > 0 {
+			return fmt.Errorf("--var requires --template")
 		}
 
-		// Get thread ID
-		var threadID uuid.UUID
+		// Find the thread to continue. A new thread is only created once there is a message.
+		var thread *domain.Thread
 		if continueFlag && threadFlag != "" {
 			return fmt.Errorf("cannot specify --target and --continue")
 		}
 		if threadFlag != "" {
-			thread, err := service.FindThreadByPartialID(ctx, threadFlag)
+			thread, err = service.FindThreadByPartialID(ctx, threadFlag)
 			if err != nil {
 				return err
 			}
-			threadID = thread.ID
 		} else if continueFlag {
-			thread, err := service.GetActiveThread(ctx)
+			thread, err = service.GetActiveThread(ctx)
 			if err != nil {
 				return err
 			}
-			threadID = thread.ID
-		} else {
-			// Create new thread
-			thread, err := service.NewThread(ctx)
+		}
+
+		// Compose the message in the editor, starting from any message given
+		if editorFlag {
+			var threadContext string
+			if thread != nil {
+				threadContext, err = recentContext(ctx, service, thread.ID, nil)
+				if err != nil {
+					return err
+				}
+			}
+			initialMessage, err = editor.Compose(initialMessage, threadContext)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Attach any MCP resources and prompts
+		initialMessage, err = expandAttachments(ctx, mcpClient, initialMessage)
+		if err != nil {
+			return fmt.Errorf("failed to attach MCP content: %w", err)
+		}
+
+		if initialMessage == "" {
+			return fmt.Errorf("no message provided")
+		}
+
+		if thread == nil {
+			thread, err = service.NewThread(ctx)
 			if err != nil {
 				return fmt.Errorf("failed to create thread: %w", err)
 			}
-			threadID = thread.ID
 		}
 
 		sendOptions := message.SendMessageOptions{
-			ThreadID: threadID,
-			Content:  initialMessage,
+			ThreadID:   thread.ID,
+			Content:    initialMessage,
+			ToolFilter: toolsFlag,
+		}
+		if noToolsFlag {
+			sendOptions.ToolFilter = []string{"!*"}
+		}
+		if schemaFlag != "" || jsonFlag {
+			sendOptions.Schema, err = loadSchema(schemaFlag)
+			if err != nil {
+				return err
+			}
+			// Print only the JSON
+			noStreamFlag = true
 		}
 
 		// Send initial message
-		if err := sendMessage(ctx, agentService, sendOptions); err != nil {
+		if err := sendMessage(ctx, cmd, agentService.SendMessage, sendOptions); err != nil {
 			return err
 		}
 
 		// Handle followup mode
 		if followupFlag {
-			reader := bufio.NewReader(os.Stdin)
-			for {
-				followupMessage, err := reader.ReadString('\n')
-				if err == io.EOF {
-					break
-				}
-				if err != nil {
-					return fmt.Errorf("failed to read input: %w", err)
-				}
-
-				followupMessage = strings.TrimSpace(followupMessage)
-				if followupMessage == "" {
-					continue
-				}
-
-				sendOptions.Content = followupMessage
-				if err := sendMessage(ctx, agentService, sendOptions); err != nil {
-					return err
-				}
-			}
+			return followup(ctx, cmd, service, agentService, sendOptions)
 		}
 
 		return nil
 	},
 }
 
-func sendMessage(ctx context.Context, agentService *agent.Agent, opts message.SendMessageOptions) error {
+// resume runs the pending tool calls of a thread stopped by a limit or
+// waiting for approval, and lets the model carry on
+func resume(ctx context.Context, cmd *cobra.Command, args []string, service *message.MessageService, agentService *agent.Agent) error {
+	switch {
+	case len(args) > 0 || templateFlag != "" || editorFlag:
+		return fmt.Errorf("--resume does not take a message")
+	case schemaFlag != "" || jsonFlag:
+		return fmt.Errorf("cannot specify --resume with --schema or --json")
+	}
+
+	// Resume the most recent thread unless another is given
+	var thread *domain.Thread
+	var err error
+	if threadFlag != "" {
+		thread, err = service.FindThreadByPartialID(ctx, threadFlag)
+	} else {
+		thread, err = service.GetActiveThread(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	opts := message.SendMessageOptions{
+		ThreadID:   thread.ID,
+		ToolFilter: toolsFlag,
+	}
+	if noToolsFlag {
+		opts.ToolFilter = []string{"!*"}
+	}
+	if err := sendMessage(ctx, cmd, agentService.Resume, opts); err != nil {
+		return err
+	}
+
+	if followupFlag {
+		return followup(ctx, cmd, service, agentService, opts)
+	}
+	return nil
+}
+
+// sendFunc is Agent.SendMessage or Agent.Resume
+type sendFunc func(ctx context.Context, opts message.SendMessageOptions) (*domain.Message, error)
+
+// stopped reports that a limit stopped the agent. The command exits with
+// app.ExitStopped, without printing its usage.
+func stopped(cmd *cobra.Command, limitErr *agent.LimitReachedError) error {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+
+	hint := "Raise the limit with --max-steps, --timeout, --token-budget or --cost-budget"
+	// The agent only stops at a limit after a response that called tools
+	if limitErr.Message != nil {
+		hint = "Run `slop msg send --resume` to run the pending tool calls and let the model carry on, or raise the limit with --max-steps, --timeout, --token-budget or --cost-budget"
+	}
+	return &app.ExitError{
+		Code: app.ExitStopped,
+		Err:  fmt.Errorf("[Stopped after %d steps and %d tokens: %s]\n[%s]", limitErr.Steps, limitErr.Tokens, limitErr.Reason, hint),
+	}
+}
+
+// pendingApproval points to --resume when a tool call needs approval
+func pendingApproval(err error) error {
+	var pendingErr *agent.PendingFunctionCallError
+	if errors.As(err, &pendingErr) {
+		return fmt.Errorf("%w, run `slop msg send --resume` to approve and run it", err)
+	}
+	return err
+}
+
+func sendMessage(ctx context.Context, cmd *cobra.Command, send sendFunc, opts message.SendMessageOptions) error {
+	if output.IsStructured() {
+		return sendMessageStructured(ctx, cmd, send, opts)
+	}
+
+	// Render Markdown on a terminal unless --raw is set. Structured output is printed as is.
+	var renderer *markdown.Renderer
+	if !rawFlag && opts.Schema == nil {
+		renderer = markdown.ForTerminal(os.Stdout)
+	}
+	printContent := func(content string) {
+		if renderer != nil {
+			renderer.Render(content)
+		} else {
+			fmt.Print(content)
+		}
+	}
+
 	if !noStreamFlag {
-		opts.StreamHandler = &CLIStreamHandler{originalCallback: func(chunk []byte) error {
-			fmt.Print(string(chunk))
-			return nil
-		}}
+		opts.StreamHandler = &CLIStreamHandler{
+			originalCallback: func(chunk []byte) error {
+				fmt.Print(string(chunk))
+				return nil
+			},
+			markdown: renderer,
+		}
 	}
 
 	errCh := make(chan error, 1)
 	go func() {
-		resp, err := agentService.SendMessage(ctx, opts)
+		resp, err := send(ctx, opts)
+		var limitErr *agent.LimitReachedError
+		if errors.As(err, &limitErr) {
+			if noStreamFlag && resp != nil {
+				printContent(resp.Content)
+			}
+			fmt.Println()
+			errCh <- stopped(cmd, limitErr)
+			return
+		}
 		if err != nil {
-			errCh <- err
+			errCh <- fmt.Errorf("failed to send message: %w", pendingApproval(err))
 			return
 		}
 		if noStreamFlag {
-			fmt.Print(resp.Content)
+			printContent(resp.Content)
 		}
 		// note: gemini does not stream tool use (is this an issue with langchaingo?)
 		errCh <- nil
@@ -174,7 +344,7 @@ func sendMessage(ctx context.Context, agentService *agent.Agent, opts message.Se
 		return ctx.Err()
 	case err := <-errCh:
 		if err != nil {
-			return fmt.Errorf("failed to send message: %w", err)
+			return err
 		}
 	}
 
@@ -182,9 +352,69 @@ func sendMessage(ctx context.Context, agentService *agent.Agent, opts message.Se
 	return nil
 }
 
+// followup reads further messages from stdin and sends them to the thread,
+// one per line. A line with only /edit composes the message in the editor.
+func followup(ctx context.Context, cmd *cobra.Command, service *message.MessageService, agentService *agent.Agent, opts message.SendMessageOptions) error {
+	// Later messages reply to the newest message in the thread
+	opts.ParentID = nil
+
+	stat, _ := os.Stdin.Stat()
+	interactive := stat != nil && stat.Mode()&os.ModeCharDevice != 0
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		if interactive {
+			fmt.Print("\nReply (/edit to use your editor): ")
+		}
+		followupMessage, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+
+		followupMessage = strings.TrimSpace(followupMessage)
+		if followupMessage == "/edit" {
+			threadContext, err := recentContext(ctx, service, opts.ThreadID, nil)
+			if err != nil {
+				return err
+			}
+			if followupMessage, err = editor.Compose("", threadContext); err != nil {
+				return err
+			}
+		}
+		if followupMessage == "" {
+			continue
+		}
+
+		opts.Content = followupMessage
+		if err := sendMessage(ctx, cmd, agentService.SendMessage, opts); err != nil {
+			return err
+		}
+	}
+}
+
+// loadSchema reads a JSON Schema file, an empty path accepts any JSON value
+func loadSchema(path string) (map[string]interface{}, error) {
+	if path == "" {
+		return map[string]interface{}{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %w", path, err)
+	}
+	return schema, nil
+}
+
 // Handles function call detection and formatting
 type CLIStreamHandler struct {
 	originalCallback func([]byte) error
+	markdown         *markdown.Renderer // Renders text when set
 	inQuote          bool
 	escaped          bool
 	indentLevel      int
@@ -193,15 +423,25 @@ type CLIStreamHandler struct {
 }
 
 func (h *CLIStreamHandler) HandleTextChunk(chunk []byte) error {
+	if h.markdown != nil {
+		_, err := h.markdown.Write(chunk)
+		return err
+	}
 	return h.originalCallback(chunk)
 }
 
 func (h *CLIStreamHandler) HandleMessageDone() error {
+	if err := h.flushMarkdown(); err != nil {
+		return err
+	}
 	fmt.Print("\n\n")
 	return nil
 }
 
 func (h *CLIStreamHandler) HandleFunctionCallStart(id, name string) error {
+	if err := h.flushMarkdown(); err != nil {
+		return err
+	}
 	fmt.Printf("\n\n[Requesting tool use: %s]", name)
 	return nil
 }
@@ -211,6 +451,26 @@ func (h *CLIStreamHandler) HandleFunctionCallChunk(chunk message.FunctionCallChu
 	return nil
 }
 
+func (h *CLIStreamHandler) HandleToolResult(id, name, result string, err error) error {
+	if err != nil {
+		fmt.Printf("\n[Tool error: %s]\n%v\n", name, err)
+		return nil
+	}
+	fmt.Printf("\n[Tool result: %s]\n%s\n", name, result)
+	return nil
+}
+
+func (h *CLIStreamHandler) HandleUsage(inputTokens, outputTokens int) error {
+	return nil
+}
+
+func (h *CLIStreamHandler) flushMarkdown() error {
+	if h.markdown == nil {
+		return nil
+	}
+	return h.markdown.Flush()
+}
+
 func (h *CLIStreamHandler) Reset() {
 	h.inQuote = false
 	h.escaped = false
@@ -303,9 +563,24 @@ func (h *CLIStreamHandler) formatJSON(data string) string {
 func init() {
 	sendCmd.Flags().StringVarP(&threadFlag, "thread", "t", "", "Continue target thread")
 	sendCmd.Flags().BoolVarP(&continueFlag, "continue", "c", false, "Continue the most recent thread")
+	sendCmd.Flags().BoolVar(&resumeFlag, "resume", false, "Run the pending tool calls of the most recent thread, or of --thread, and let the model carry on")
 	sendCmd.Flags().BoolVarP(&followupFlag, "followup", "f", false, "Enable followup mode")
+	sendCmd.Flags().BoolVarP(&editorFlag, "editor", "e", false, "Compose the message in $EDITOR, starting from any message given")
 	sendCmd.Flags().StringVarP(&modelFlag, "model", "m", "", "Specify the model to use")
 	sendCmd.Flags().BoolVarP(&noStreamFlag, "no-stream", "n", false, "Disable streaming of responses")
+	sendCmd.Flags().BoolVar(&rawFlag, "raw", false, "Print responses as is instead of rendering Markdown")
 	sendCmd.Flags().IntVar(&maxTokensFlag, "max-tokens", 0, "Override maximum length")
 	sendCmd.Flags().Float64Var(&temperatureFlag, "temperature", 0, "Override temperature")
+	sendCmd.Flags().StringSliceVar(&toolsFlag, "tools", nil, "Only offer tools matching these globs, prefix with ! to exclude, e.g. --tools 'filesystem__*,!filesystem__write_file'")
+	sendCmd.Flags().BoolVar(&noToolsFlag, "no-tools", false, "Do not offer any tools")
+	sendCmd.Flags().IntVar(&maxStepsFlag, "max-steps", 0, "Override the maximum number of model calls, 0 for no limit")
+	sendCmd.Flags().DurationVar(&timeoutFlag, "timeout", 0, "Override the overall time limit, e.g. 10m, 0 for no limit")
+	sendCmd.Flags().IntVar(&tokenBudgetFlag, "token-budget", 0, "Override the token budget, 0 for no limit")
+	sendCmd.Flags().Float64Var(&costBudgetFlag, "cost-budget", 0, "Override the cost budget in USD, 0 for no limit")
+	sendCmd.Flags().StringVar(&schemaFlag, "schema", "", "Respond with JSON matching this JSON Schema file and print only the JSON")
+	sendCmd.Flags().BoolVar(&jsonFlag, "json", false, "Respond with any JSON value and print only the JSON")
+	sendCmd.Flags().StringArrayVar(&resourceFlags, "resource", nil, "Attach an MCP resource by uri, or as server://path (repeatable)")
+	sendCmd.Flags().StringVar(&templateFlag, "template", "", "Send a prompt template, followed by any message given (see slop prompts ls)")
+	sendCmd.Flags().StringArrayVar(&varFlags, "var", nil, "Set a prompt template variable, e.g. --var file=main.go (repeatable)")
+	sendCmd.Flags().StringArrayVar(&promptFlags, "prompt", nil, "Expand an MCP prompt, e.g. --prompt \"server:name key=value\" (repeatable)")
 }
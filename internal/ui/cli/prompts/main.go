@@ -0,0 +1,69 @@
+package prompts
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/isaacphi/slop/internal/prompts"
+	"github.com/isaacphi/slop/internal/ui/output"
+	"github.com/spf13/cobra"
+)
+
+var varFlags []string
+
+var PromptsCmd = &cobra.Command{
+	Use:   "prompts",
+	Short: "Manage prompt templates",
+	Long: `Prompt templates are files in .slop/prompts/ and the prompts directory of the
+global config directory. They use Go template syntax with variables given by
+--var key=value, and can include files with {{ include "path" }} and command
+output with {{ shell "command" }}. Send one with slop msg send --template name.`,
+}
+
+var listCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List prompt templates",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		templates, err := prompts.List()
+		if err != nil {
+			return err
+		}
+
+		if output.IsStructured() {
+			return output.Render(os.Stdout, templates)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Name\tSource\tDescription")
+		for _, t := range templates {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", t.Name, t.Source, t.Description)
+		}
+		w.Flush()
+
+		return nil
+	},
+}
+
+var renderCmd = &cobra.Command{
+	Use:   "render [name]",
+	Short: "Print a rendered prompt template",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vars, err := prompts.ParseVars(varFlags)
+		if err != nil {
+			return err
+		}
+		rendered, err := prompts.Render(args[0], vars)
+		if err != nil {
+			return err
+		}
+		fmt.Println(rendered)
+		return nil
+	},
+}
+
+func init() {
+	renderCmd.Flags().StringArrayVar(&varFlags, "var", nil, "Set a template variable, e.g. --var file=main.go (repeatable)")
+	PromptsCmd.AddCommand(listCmd, renderCmd)
+}
@@ -1,21 +1,31 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/isaacphi/slop/internal/app"
 	"github.com/isaacphi/slop/internal/config"
+	"github.com/isaacphi/slop/internal/ui/cli/auth"
 	configCmd "github.com/isaacphi/slop/internal/ui/cli/config"
+	"github.com/isaacphi/slop/internal/ui/cli/daemon"
 	"github.com/isaacphi/slop/internal/ui/cli/mcp"
 	"github.com/isaacphi/slop/internal/ui/cli/msg"
+	"github.com/isaacphi/slop/internal/ui/cli/prompts"
 	"github.com/isaacphi/slop/internal/ui/cli/thread"
+	"github.com/isaacphi/slop/internal/ui/cli/tools"
+	"github.com/isaacphi/slop/internal/ui/output"
 	"github.com/spf13/cobra"
 )
 
 var (
-	logLevel string
-	logFile  string
+	logLevel     string
+	logFile      string
+	outputFormat string
+	profile      string
+	setFlags     []string
+	configDir    string
 )
 
 var rootCmd = &cobra.Command{
@@ -28,6 +38,10 @@ var rootCmd = &cobra.Command{
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		var exitErr *app.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
@@ -36,8 +50,20 @@ func init() {
 	// Add global flags for logging
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "Set logging level (DEBUG, INFO, WARN, ERROR)")
 	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Log file path (defaults to stdout)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "text", "Output format (text, json, yaml, ndjson)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Config profile to use (defaults to $SLOP_PROFILE)")
+	rootCmd.PersistentFlags().StringArrayVar(&setFlags, "set", nil, "Override a config value, e.g. --set agent.maxSteps=50 (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&configDir, "config-dir", "", "Project config directory (defaults to the nearest .slop directory)")
 
 	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := output.SetFormat(outputFormat); err != nil {
+			return err
+		}
+		config.SetLocalDir(configDir)
+		if _, ok := cmd.Annotations[app.SkipInit]; ok {
+			return nil
+		}
+
 		// Initialize app with logging overrides
 		overrides := &config.RuntimeOverrides{}
 		if logLevel != "" {
@@ -46,6 +72,10 @@ func init() {
 		if logFile != "" {
 			overrides.LogFile = &logFile
 		}
+		if cmd.Flags().Changed("profile") {
+			overrides.Profile = &profile
+		}
+		overrides.Set = setFlags
 		return app.Initialize(overrides)
 	}
 
@@ -61,5 +91,9 @@ func init() {
 		msg.MsgCmd,
 		thread.ThreadCmd,
 		mcp.MCPCmd,
+		daemon.DaemonCmd,
+		tools.ToolsCmd,
+		prompts.PromptsCmd,
+		auth.AuthCmd,
 	)
 }
@@ -8,9 +8,19 @@ import (
 
 	"github.com/isaacphi/slop/internal/app"
 	"github.com/isaacphi/slop/internal/message"
+	"github.com/isaacphi/slop/internal/ui/output"
 	"github.com/spf13/cobra"
 )
 
+// threadItem is a thread as printed by the structured output formats
+type threadItem struct {
+	ID           string    `json:"id"`
+	CreatedAt    time.Time `json:"createdAt"`
+	MessageCount int       `json:"messageCount"`
+	Summary      string    `json:"summary,omitempty"`
+	Preview      string    `json:"preview"`
+}
+
 var listCmd = &cobra.Command{
 	Use:   "ls",
 	Short: "List conversation threads",
@@ -26,6 +36,24 @@ var listCmd = &cobra.Command{
 			return fmt.Errorf("failed to list threads: %w", err)
 		}
 
+		if output.IsStructured() {
+			items := make([]threadItem, 0, len(threads))
+			for _, thread := range threads {
+				summary, err := service.GetThreadDetails(cmd.Context(), thread)
+				if err != nil {
+					return fmt.Errorf("failed to get thread summary: %w", err)
+				}
+				items = append(items, threadItem{
+					ID:           summary.ID.String(),
+					CreatedAt:    summary.CreatedAt,
+					MessageCount: summary.MessageCount,
+					Summary:      thread.Summary,
+					Preview:      summary.Preview,
+				})
+			}
+			return output.Render(os.Stdout, items)
+		}
+
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 		fmt.Fprintln(w, "ID\tCreated\tMessages\tPreview")
 
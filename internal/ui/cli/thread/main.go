@@ -7,6 +7,7 @@ import (
 var (
 	limitFlag int
 	forceFlag bool
+	rawFlag   bool
 )
 
 var ThreadCmd = &cobra.Command{
@@ -17,6 +18,7 @@ var ThreadCmd = &cobra.Command{
 func init() {
 	listCmd.Flags().IntVarP(&limitFlag, "limit", "n", 0, "Limit the number of threads to show (0 for all)")
 	viewCmd.Flags().IntVarP(&limitFlag, "limit", "n", 0, "Limit the number of messages to show (0 for all)")
+	viewCmd.Flags().BoolVar(&rawFlag, "raw", false, "Print messages as is instead of rendering Markdown")
 	deleteCmd.Flags().BoolVarP(&forceFlag, "force", "f", false, "Delete without confirmation")
 
 	ThreadCmd.AddCommand(listCmd, viewCmd, deleteCmd, summaryCmd)
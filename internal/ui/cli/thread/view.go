@@ -1,15 +1,60 @@
 package thread
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/isaacphi/slop/internal/app"
 	"github.com/isaacphi/slop/internal/domain"
 	"github.com/isaacphi/slop/internal/message"
+	"github.com/isaacphi/slop/internal/ui/markdown"
+	"github.com/isaacphi/slop/internal/ui/output"
 	"github.com/spf13/cobra"
 )
 
+// threadView is a thread and its messages as printed by the structured output formats
+type threadView struct {
+	ID        string        `json:"id"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Summary   string        `json:"summary,omitempty"`
+	Messages  []messageItem `json:"messages"`
+}
+
+type messageItem struct {
+	ID           string          `json:"id"`
+	ParentID     string          `json:"parentId,omitempty"`
+	Role         domain.Role     `json:"role"`
+	Content      string          `json:"content"`
+	ToolCalls    json.RawMessage `json:"toolCalls,omitempty"`
+	ModelName    string          `json:"modelName,omitempty"`
+	Provider     string          `json:"provider,omitempty"`
+	InputTokens  int             `json:"inputTokens,omitempty"`
+	OutputTokens int             `json:"outputTokens,omitempty"`
+	CreatedAt    time.Time       `json:"createdAt"`
+}
+
+func newMessageItem(msg domain.Message) messageItem {
+	item := messageItem{
+		ID:           msg.ID.String(),
+		Role:         msg.Role,
+		Content:      msg.Content,
+		ModelName:    msg.ModelName,
+		Provider:     msg.Provider,
+		InputTokens:  msg.InputTokens,
+		OutputTokens: msg.OutputTokens,
+		CreatedAt:    msg.CreatedAt,
+	}
+	if msg.ParentID != nil {
+		item.ParentID = msg.ParentID.String()
+	}
+	if msg.ToolCalls != "" && json.Valid([]byte(msg.ToolCalls)) {
+		item.ToolCalls = json.RawMessage(msg.ToolCalls)
+	}
+	return item
+}
+
 var viewCmd = &cobra.Command{
 	Use:   "view [thread_id]",
 	Short: "View messages in a thread",
@@ -31,13 +76,31 @@ var viewCmd = &cobra.Command{
 			return fmt.Errorf("failed to get thread messages: %w", err)
 		}
 
+		if limitFlag > 0 && len(messages) > limitFlag {
+			messages = messages[len(messages)-limitFlag:]
+		}
+
+		if output.IsStructured() {
+			view := threadView{
+				ID:        thread.ID.String(),
+				CreatedAt: thread.CreatedAt,
+				Summary:   thread.Summary,
+				Messages:  make([]messageItem, 0, len(messages)),
+			}
+			for _, msg := range messages {
+				view.Messages = append(view.Messages, newMessageItem(msg))
+			}
+			return output.Render(os.Stdout, view)
+		}
+
 		fmt.Printf("Thread %s (created %s)\n\n",
 			thread.ID.String()[:8],
 			thread.CreatedAt.Format(time.RFC822),
 		)
 
-		if limitFlag > 0 && len(messages) > limitFlag {
-			messages = messages[len(messages)-limitFlag:]
+		var renderer *markdown.Renderer
+		if !rawFlag {
+			renderer = markdown.ForTerminal(os.Stdout)
 		}
 
 		for _, msg := range messages {
@@ -45,7 +108,15 @@ var viewCmd = &cobra.Command{
 			if msg.Role == domain.RoleAssistant {
 				roleStr = "Slop"
 			}
-			fmt.Printf("%s - %s: %s\n", msg.ID.String()[:8], roleStr, msg.Content)
+			if renderer == nil || msg.Role != domain.RoleAssistant {
+				fmt.Printf("%s - %s: %s\n", msg.ID.String()[:8], roleStr, msg.Content)
+				continue
+			}
+			fmt.Printf("%s - %s:\n", msg.ID.String()[:8], roleStr)
+			if err := renderer.Render(msg.Content); err != nil {
+				return err
+			}
+			fmt.Println()
 		}
 
 		return nil
@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/isaacphi/slop/internal/app"
+	"github.com/isaacphi/slop/internal/domain"
+	"github.com/isaacphi/slop/internal/message"
+	"github.com/isaacphi/slop/internal/ui/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	threadFlag  string
+	toolFlag    string
+	sinceFlag   string
+	untilFlag   string
+	limitFlag   int
+	verboseFlag bool
+)
+
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Show the audit log of tool executions",
+	Long: `Show recorded tool executions, newest first.
+
+Times for --since and --until are either a duration before now such as 24h,
+or a date such as 2006-01-02, 2006-01-02T15:04 or RFC 3339.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := app.Get().Config
+		service, err := message.InitializeMessageService(cfg, nil)
+		if err != nil {
+			return err
+		}
+
+		filter := domain.ToolExecutionFilter{
+			Tool:  toolFlag,
+			Limit: limitFlag,
+		}
+		if threadFlag != "" {
+			thread, err := service.FindThreadByPartialID(cmd.Context(), threadFlag)
+			if err != nil {
+				return fmt.Errorf("failed to find thread: %w", err)
+			}
+			filter.ThreadID = &thread.ID
+		}
+		if sinceFlag != "" {
+			if filter.Since, err = parseTime(sinceFlag); err != nil {
+				return err
+			}
+		}
+		if untilFlag != "" {
+			if filter.Until, err = parseTime(untilFlag); err != nil {
+				return err
+			}
+		}
+
+		executions, err := service.ListToolExecutions(cmd.Context(), filter)
+		if err != nil {
+			return fmt.Errorf("failed to list tool executions: %w", err)
+		}
+
+		if output.IsStructured() {
+			items := make([]executionItem, 0, len(executions))
+			for _, exec := range executions {
+				items = append(items, newExecutionItem(exec))
+			}
+			return output.Render(os.Stdout, items)
+		}
+
+		if verboseFlag {
+			for _, exec := range executions {
+				printExecution(exec)
+			}
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Time\tThread\tTool\tApproval\tBy\tDuration\tStatus")
+		for _, exec := range executions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				exec.CreatedAt.Format(time.RFC822),
+				exec.ThreadID.String()[:8],
+				exec.Tool,
+				exec.Approval,
+				exec.ApprovedBy,
+				exec.Duration.Round(time.Millisecond),
+				status(exec),
+			)
+		}
+		w.Flush()
+
+		return nil
+	},
+}
+
+// executionItem is a tool execution as printed by the structured output formats
+type executionItem struct {
+	ID         string                  `json:"id"`
+	CallID     string                  `json:"callId"`
+	ThreadID   string                  `json:"threadId"`
+	MessageID  string                  `json:"messageId"`
+	Server     string                  `json:"server"`
+	Tool       string                  `json:"tool"`
+	Arguments  json.RawMessage         `json:"arguments,omitempty"`
+	Result     string                  `json:"result,omitempty"`
+	Error      string                  `json:"error,omitempty"`
+	DurationMs int64                   `json:"durationMs"`
+	Approval   domain.ApprovalDecision `json:"approval"`
+	ApprovedBy string                  `json:"approvedBy,omitempty"`
+	Status     string                  `json:"status"`
+	CreatedAt  time.Time               `json:"createdAt"`
+}
+
+func newExecutionItem(exec domain.ToolExecution) executionItem {
+	item := executionItem{
+		ID:         exec.ID.String(),
+		CallID:     exec.CallID,
+		ThreadID:   exec.ThreadID.String(),
+		MessageID:  exec.MessageID.String(),
+		Server:     exec.Server,
+		Tool:       exec.Tool,
+		Result:     exec.Result,
+		Error:      exec.Error,
+		DurationMs: exec.Duration.Milliseconds(),
+		Approval:   exec.Approval,
+		ApprovedBy: exec.ApprovedBy,
+		Status:     status(exec),
+		CreatedAt:  exec.CreatedAt,
+	}
+	if json.Valid([]byte(exec.Arguments)) {
+		item.Arguments = json.RawMessage(exec.Arguments)
+	}
+	return item
+}
+
+func printExecution(exec domain.ToolExecution) {
+	fmt.Printf("%s %s\n", exec.CreatedAt.Format(time.RFC3339), exec.Tool)
+	fmt.Printf("  id: %s\n", exec.ID)
+	fmt.Printf("  call: %s\n", exec.CallID)
+	fmt.Printf("  thread: %s\n", exec.ThreadID)
+	fmt.Printf("  message: %s\n", exec.MessageID)
+	fmt.Printf("  server: %s\n", exec.Server)
+	fmt.Printf("  approval: %s\n", exec.Approval)
+	if exec.ApprovedBy != "" {
+		fmt.Printf("  by: %s\n", exec.ApprovedBy)
+	}
+	fmt.Printf("  duration: %s\n", exec.Duration)
+	fmt.Printf("  arguments: %s\n", exec.Arguments)
+	if exec.Error != "" {
+		fmt.Printf("  error: %s\n", indent(exec.Error))
+	} else if exec.Result != "" {
+		fmt.Printf("  result: %s\n", indent(exec.Result))
+	}
+	fmt.Println()
+}
+
+func status(exec domain.ToolExecution) string {
+	switch {
+	case exec.Approval != domain.ApprovalApproved:
+		return "-"
+	case exec.Error != "":
+		return "error"
+	case exec.Duration == 0 && exec.Result == "":
+		// Recorded but never completed, e.g. slop was killed mid-call
+		return "incomplete"
+	}
+	return "ok"
+}
+
+func indent(s string) string {
+	return strings.ReplaceAll(strings.TrimRight(s, "\n"), "\n", "\n    ")
+}
+
+// parseTime accepts a duration before now or an absolute date
+func parseTime(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, use a duration such as 24h or a date such as 2006-01-02", value)
+}
+
+func init() {
+	logCmd.Flags().StringVarP(&threadFlag, "thread", "t", "", "Only show executions in this thread")
+	logCmd.Flags().StringVar(&toolFlag, "tool", "", "Only show tools matching this glob, e.g. filesystem__*")
+	logCmd.Flags().StringVar(&sinceFlag, "since", "", "Only show executions after this time")
+	logCmd.Flags().StringVar(&untilFlag, "until", "", "Only show executions before this time")
+	logCmd.Flags().IntVarP(&limitFlag, "limit", "n", 50, "Limit the number of executions to show (0 for all)")
+	logCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "Show arguments and results")
+}
@@ -0,0 +1,14 @@
+package tools
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var ToolsCmd = &cobra.Command{
+	Use:   "tools",
+	Short: "Inspect tool usage",
+}
+
+func init() {
+	ToolsCmd.AddCommand(logCmd)
+}
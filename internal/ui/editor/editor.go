@@ -0,0 +1,88 @@
+// Package editor opens the user's editor to compose text.
+package editor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Scissors separates the text being composed from the context shown below it
+const Scissors = "# ------------------------ >8 ------------------------"
+
+// Command returns the editor to run, from $VISUAL or $EDITOR, defaulting to vi
+func Command() string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(env)); editor != "" {
+			return editor
+		}
+	}
+	return "vi"
+}
+
+// Compose opens the editor on a scratch file containing initial, followed by
+// context as comments below a scissors line, and returns the text saved above
+// the scissors line with surrounding whitespace removed. Markdown headings
+// are kept, so only the scissors line marks where the message ends.
+func Compose(initial, context string) (string, error) {
+	f, err := os.CreateTemp("", "slop-message-*.md")
+	if err != nil {
+		return "", fmt.Errorf("failed to create message file: %w", err)
+	}
+	path := f.Name()
+	defer os.Remove(path)
+
+	var content strings.Builder
+	content.WriteString(initial)
+	content.WriteString("\n\n")
+	content.WriteString(Scissors)
+	content.WriteString("\n# Write your message above. Everything below this line is ignored.\n")
+	if context != "" {
+		content.WriteString("#\n")
+		for _, line := range strings.Split(strings.TrimRight(context, "\n"), "\n") {
+			content.WriteString(strings.TrimRight("# "+line, " "))
+			content.WriteString("\n")
+		}
+	}
+	if _, err := f.WriteString(content.String()); err != nil {
+		f.Close()
+		return "", fmt.Errorf("failed to write message file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write message file: %w", err)
+	}
+
+	if err := Open(path); err != nil {
+		return "", err
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read message file: %w", err)
+	}
+	text, _, _ := strings.Cut(string(saved), Scissors)
+	return strings.TrimSpace(text), nil
+}
+
+// Open edits path in the editor, attached to the terminal even when stdin is piped
+func Open(path string) error {
+	editor := Command()
+	// The editor may include arguments, e.g. "code --wait"
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice == 0 {
+		if tty, err := os.Open("/dev/tty"); err == nil {
+			defer tty.Close()
+			cmd.Stdin = tty
+		}
+	}
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %w", editor, err)
+	}
+	return nil
+}
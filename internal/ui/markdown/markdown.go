@@ -0,0 +1,375 @@
+// Package markdown renders Markdown for the terminal as it streams in.
+//
+// Text is rendered a line at a time as lines complete, except for fenced code
+// blocks and tables which are buffered until they end so code can be
+// highlighted as a whole and table columns aligned. Without color the
+// structure is still rendered, lists and tables are aligned, but inline
+// markup is left as written.
+package markdown
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+const (
+	bold      = "\x1b[1m"
+	dim       = "\x1b[2m"
+	italic    = "\x1b[3m"
+	underline = "\x1b[4m"
+	cyan      = "\x1b[36m"
+	magenta   = "\x1b[35m"
+	reset     = "\x1b[0m"
+
+	codeStyle = "monokai"
+	ruleWidth = 40
+)
+
+var (
+	headingRe  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	bulletRe   = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedRe  = regexp.MustCompile(`^(\s*)(\d+[.)])\s+(.*)$`)
+	ruleRe     = regexp.MustCompile(`^\s{0,3}([-*_])(\s*([-*_])){2,}\s*$`)
+	delimRe    = regexp.MustCompile(`^\s*:?-+:?\s*$`)
+	ansiRe     = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	linkRe     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	boldRe     = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	italicRe   = regexp.MustCompile(`(^|[^*\w])\*([^*\s][^*]*)\*`)
+	codeSpanRe = regexp.MustCompile("`([^`]+)`")
+)
+
+// Renderer is an io.Writer that renders the Markdown written to it. Flush
+// must be called at the end of a message to render what is still buffered.
+type Renderer struct {
+	w     io.Writer
+	color bool
+
+	line []byte // Incomplete line
+
+	// Fenced code block being buffered
+	inFence bool
+	fence   string
+	lang    string
+	code    strings.Builder
+
+	table []string // Table rows being buffered
+}
+
+// New returns a renderer writing to w, using ANSI styles if color is set
+func New(w io.Writer, color bool) *Renderer {
+	return &Renderer{w: w, color: color}
+}
+
+// ForTerminal returns a renderer for f, or nil if f is not a terminal and
+// output should be written as is. Colors are off when NO_COLOR is set.
+func ForTerminal(f *os.File) *Renderer {
+	stat, err := f.Stat()
+	if err != nil || stat.Mode()&os.ModeCharDevice == 0 || os.Getenv("TERM") == "dumb" {
+		return nil
+	}
+	_, noColor := os.LookupEnv("NO_COLOR")
+	return New(f, !noColor)
+}
+
+// Write renders every complete line in p and buffers the rest
+func (r *Renderer) Write(p []byte) (int, error) {
+	r.line = append(r.line, p...)
+	for {
+		i := bytes.IndexByte(r.line, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(r.line[:i])
+		r.line = r.line[i+1:]
+		if err := r.renderLine(line); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// Flush renders any incomplete line and unterminated block
+func (r *Renderer) Flush() error {
+	if len(r.line) > 0 {
+		line := string(r.line)
+		r.line = nil
+		if err := r.renderLine(line); err != nil {
+			return err
+		}
+	}
+	if r.inFence {
+		r.inFence = false
+		if err := r.writeCode(); err != nil {
+			return err
+		}
+	}
+	return r.writeTable()
+}
+
+// Render renders a complete document
+func (r *Renderer) Render(s string) error {
+	if _, err := io.WriteString(r, s); err != nil {
+		return err
+	}
+	return r.Flush()
+}
+
+func (r *Renderer) renderLine(line string) error {
+	line = strings.TrimSuffix(line, "\r")
+	trimmed := strings.TrimSpace(line)
+
+	if r.inFence {
+		if strings.HasPrefix(trimmed, r.fence) && strings.Trim(trimmed, r.fence[:1]) == "" {
+			r.inFence = false
+			return r.writeCode()
+		}
+		r.code.WriteString(line)
+		r.code.WriteByte('\n')
+		return nil
+	}
+
+	if strings.HasPrefix(trimmed, "|") {
+		r.table = append(r.table, trimmed)
+		return nil
+	}
+	if err := r.writeTable(); err != nil {
+		return err
+	}
+
+	if fence := fenceMarker(trimmed); fence != "" {
+		r.inFence = true
+		r.fence = fence
+		r.lang = strings.TrimSpace(strings.TrimLeft(trimmed, fence[:1]))
+		if i := strings.IndexAny(r.lang, " {"); i >= 0 {
+			r.lang = r.lang[:i]
+		}
+		r.code.Reset()
+		return nil
+	}
+
+	return r.writeLine(r.formatLine(line))
+}
+
+// fenceMarker returns the run of backticks or tildes opening a code block
+func fenceMarker(line string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 && !(c == "`" && strings.Contains(line[n:], "`")) {
+			return strings.Repeat(c, n)
+		}
+	}
+	return ""
+}
+
+func (r *Renderer) formatLine(line string) string {
+	if m := headingRe.FindStringSubmatch(line); m != nil {
+		if !r.color {
+			return line
+		}
+		style := bold
+		if len(m[1]) <= 2 {
+			style = bold + magenta
+		}
+		if len(m[1]) == 1 {
+			style += underline
+		}
+		return style + r.inline(m[2]) + reset
+	}
+	if ruleRe.MatchString(line) {
+		return r.style(dim, strings.Repeat("─", ruleWidth))
+	}
+	if m := bulletRe.FindStringSubmatch(line); m != nil {
+		return m[1] + "• " + r.inline(m[2])
+	}
+	if m := orderedRe.FindStringSubmatch(line); m != nil {
+		return m[1] + m[2] + " " + r.inline(m[3])
+	}
+	if strings.HasPrefix(strings.TrimSpace(line), ">") {
+		text := strings.TrimPrefix(strings.TrimSpace(line), ">")
+		return r.style(dim, "│ ") + r.style(italic, r.inline(strings.TrimPrefix(text, " ")))
+	}
+	return r.inline(line)
+}
+
+// inline renders code spans, bold, italic and links
+func (r *Renderer) inline(s string) string {
+	if !r.color {
+		return s
+	}
+
+	// Format the text between code spans so markup inside them is kept
+	var out strings.Builder
+	last := 0
+	for _, m := range codeSpanRe.FindAllStringSubmatchIndex(s, -1) {
+		out.WriteString(r.emphasis(s[last:m[0]]))
+		out.WriteString(cyan + s[m[2]:m[3]] + reset)
+		last = m[1]
+	}
+	out.WriteString(r.emphasis(s[last:]))
+	return out.String()
+}
+
+func (r *Renderer) emphasis(s string) string {
+	s = linkRe.ReplaceAllString(s, underline+"$1"+reset+dim+" ($2)"+reset)
+	s = boldRe.ReplaceAllString(s, bold+"$1$2"+reset)
+	return italicRe.ReplaceAllString(s, "$1"+italic+"$2"+reset)
+}
+
+func (r *Renderer) style(style, s string) string {
+	if !r.color {
+		return s
+	}
+	return style + s + reset
+}
+
+func (r *Renderer) writeLine(s string) error {
+	_, err := io.WriteString(r.w, s+"\n")
+	return err
+}
+
+// writeCode writes the buffered code block, indented and highlighted
+func (r *Renderer) writeCode() error {
+	code := r.code.String()
+	r.code.Reset()
+	if r.color {
+		code = highlight(code, r.lang)
+	}
+	lines := strings.Split(code, "\n")
+	// Highlighting can leave a reset after the final newline, keep it on the last line
+	if n := len(lines); n > 1 && visibleWidth(lines[n-1]) == 0 {
+		lines[n-2] += lines[n-1]
+		lines = lines[:n-1]
+	}
+	for _, line := range lines {
+		if err := r.writeLine("  " + line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// highlight colors code for a 256 color terminal, guessing the language if
+// it is not given. Code is returned as is if it cannot be highlighted.
+func highlight(code, lang string) string {
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		lexer = lexers.Analyse(code)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return code
+	}
+	var out strings.Builder
+	if err := formatters.TTY256.Format(&out, styles.Get(codeStyle), iterator); err != nil {
+		return code
+	}
+	return out.String()
+}
+
+// writeTable writes the buffered table rows with aligned columns
+func (r *Renderer) writeTable() error {
+	if len(r.table) == 0 {
+		return nil
+	}
+	rows := make([][]string, 0, len(r.table))
+	header := -1
+	for _, line := range r.table {
+		cells := splitRow(line)
+		if header < 0 && len(rows) == 1 && isDelimiterRow(cells) {
+			header = 0
+			continue
+		}
+		for i := range cells {
+			cells[i] = r.inline(cells[i])
+		}
+		rows = append(rows, cells)
+	}
+	r.table = nil
+
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], visibleWidth(cell))
+		}
+	}
+
+	for i, row := range rows {
+		cells := make([]string, len(widths))
+		for j := range widths {
+			var cell string
+			if j < len(row) {
+				cell = row[j]
+			}
+			if i == header {
+				cell = r.style(bold, cell)
+			}
+			if j < len(widths)-1 {
+				cell += strings.Repeat(" ", widths[j]-visibleWidth(cell))
+			}
+			cells[j] = cell
+		}
+		if err := r.writeLine(strings.Join(cells, r.style(dim, " │ "))); err != nil {
+			return err
+		}
+		if i == header {
+			rules := make([]string, len(widths))
+			for j, w := range widths {
+				rules[j] = strings.Repeat("─", w)
+			}
+			if err := r.writeLine(r.style(dim, strings.Join(rules, "─┼─"))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// splitRow splits a table row into trimmed cells, keeping escaped pipes
+func splitRow(line string) []string {
+	line = strings.TrimPrefix(strings.TrimSpace(line), "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+func isDelimiterRow(cells []string) bool {
+	for _, cell := range cells {
+		if !delimRe.MatchString(cell) {
+			return false
+		}
+	}
+	return len(cells) > 0
+}
+
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(ansiRe.ReplaceAllString(s, ""))
+}
@@ -0,0 +1,113 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is how commands print their results
+type Format string
+
+const (
+	Text   Format = "text"
+	JSON   Format = "json"
+	YAML   Format = "yaml"
+	NDJSON Format = "ndjson" // One JSON value per line; lists print one element per line
+)
+
+var current = Text
+
+// SetFormat selects the output format for the current command
+func SetFormat(value string) error {
+	switch f := Format(value); f {
+	case Text, JSON, YAML, NDJSON:
+		current = f
+		return nil
+	case "":
+		current = Text
+		return nil
+	default:
+		return fmt.Errorf("invalid output format %q, must be one of text, json, yaml, ndjson", value)
+	}
+}
+
+// Get returns the selected output format
+func Get() Format {
+	return current
+}
+
+// IsStructured reports whether a machine-readable format was selected.
+// Commands print their human output when it is false.
+func IsStructured() bool {
+	return current != Text
+}
+
+// Render writes v in the selected structured format. Field names come from
+// json tags in every format so they are stable across formats.
+func Render(w io.Writer, v interface{}) error {
+	switch current {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case NDJSON:
+		return renderNDJSON(w, v)
+	case YAML:
+		return renderYAML(w, v)
+	default:
+		return fmt.Errorf("no renderer for output format %s", current)
+	}
+}
+
+func renderNDJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return enc.Encode(v)
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := enc.Encode(rv.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderYAML converts through JSON, which YAML parses as is, so that keys and
+// field order match the JSON output
+func renderYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	clearStyle(&node)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// clearStyle switches nodes parsed from JSON to block style
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}